@@ -1,26 +1,48 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/congdv/go-auth/api/internal/config"
 	"github.com/congdv/go-auth/api/internal/database"
 	"github.com/congdv/go-auth/api/internal/http/handlers"
 	"github.com/congdv/go-auth/api/internal/http/middleware"
+	"github.com/congdv/go-auth/api/internal/importers"
+	"github.com/congdv/go-auth/api/internal/jwtkeys"
+	"github.com/congdv/go-auth/api/internal/mailer"
+	"github.com/congdv/go-auth/api/internal/oauth"
 	"github.com/congdv/go-auth/api/internal/repository"
 	"github.com/congdv/go-auth/api/internal/services"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	shutdownTimeout  = 15 * time.Second
+	mailDrainTimeout = 30 * time.Second
+)
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("config error: %v", err)
 	}
+	// An empty secret would make every HS256 token forgeable. Refresh
+	// tokens always use one; access tokens do unless JWT_KEYS_DIR is set.
+	if cfg.JWTRefreshSecret == "" {
+		log.Fatal("config error: JWT_REFRESH_SECRET is required")
+	}
+	if cfg.JWTKeysDir == "" && cfg.JWTAccessSecret == "" {
+		log.Fatal("config error: JWT_ACCESS_SECRET is required unless JWT_KEYS_DIR is set")
+	}
 
 	db, err := database.Connect(cfg.DBURL)
 
@@ -30,18 +52,85 @@ func main() {
 
 	defer db.Close()
 
+	mailSender, err := mailer.NewSender(cfg)
+	if err != nil {
+		log.Fatalf("mailer error: %v", err)
+	}
+	mailQueue := mailer.NewQueue(mailSender, mailer.QueueOptions{
+		Size:        cfg.MailQueueSize,
+		MaxAttempts: cfg.MailMaxAttempts,
+	})
+	mail, err := mailer.New(mailQueue, cfg.MailFrom)
+	if err != nil {
+		log.Fatalf("mailer error: %v", err)
+	}
+
+	var signingKeys *jwtkeys.KeySet
+	if cfg.JWTKeysDir != "" {
+		signingKeys, err = jwtkeys.Load(cfg.JWTKeysDir)
+		if err != nil {
+			log.Fatalf("jwt keys error: %v", err)
+		}
+	}
+
 	userRepo := repository.NewUserRepo(db)
 	roleRepo := repository.NewRoleRepo(db)
 	tokenRepo := repository.NewTokenRepo(db)
+	passwordResetRepo := repository.NewPasswordResetRepo(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepo(db)
+	identityRepo := repository.NewIdentityRepo(db)
+	mfaRepo := repository.NewMFARepo(db)
+	passkeyRepo := repository.NewPasskeyRepo(db)
+	securityEventRepo := repository.NewSecurityEventRepo(db)
+	promptRepo := repository.NewPromptRepo(db)
+	categoryRepo := repository.NewCategoryRepo(db)
+	tagRepo := repository.NewTagRepo(db)
+	favoriteRepo := repository.NewFavoriteRepo(db)
+	shareLinkRepo := repository.NewShareLinkRepo(db)
+	exportRepo := repository.NewExportRepo(db)
+	importRepo := repository.NewImportRepo(db)
+	searchRepo := repository.NewSearchRepo(db)
+	searchHistoryRepo := repository.NewSearchHistoryRepo(db)
+	savedSearchRepo := repository.NewSavedSearchRepo(db)
+	personalTokenRepo := repository.NewPersonalTokenRepo(db)
+
+	var revocations services.RevocationStore
+	switch cfg.RevocationStore {
+	case config.RevocationMemory:
+		revocations = services.NewMemoryRevocationStore(time.Duration(cfg.JWTAccessTTLMinutes) * time.Minute)
+	case config.RevocationPostgres:
+		revocations = repository.NewRevocationRepo(db)
+	default:
+		log.Fatalf("config error: unknown REVOCATION_STORE %q", cfg.RevocationStore)
+	}
 
-	authService := services.NewAuthService(cfg, userRepo, roleRepo, tokenRepo)
+	emailPolicy := services.NewEmailPolicy(cfg, userRepo)
+	authService := services.NewAuthService(cfg, signingKeys, userRepo, roleRepo, tokenRepo, passwordResetRepo, emailVerificationRepo, identityRepo, mfaRepo, securityEventRepo, revocations, personalTokenRepo, services.NewMailNotifier(mail))
+	mfaService := services.NewMFAService(cfg, userRepo, mfaRepo)
+	sessionService := services.NewSessionService(cfg, tokenRepo, revocations)
+	roleService := services.NewRoleService(userRepo, roleRepo, revocations)
+	identityService := services.NewIdentityService(cfg, userRepo, identityRepo)
+	personalTokenService := services.NewPersonalTokenService(personalTokenRepo)
+	passkeyService, err := services.NewPasskeyService(cfg, userRepo, passkeyRepo, securityEventRepo)
+	if err != nil {
+		log.Fatalf("webauthn config error: %v", err)
+	}
+	promptService := services.NewPromptService(promptRepo, categoryRepo, tagRepo, savedSearchRepo, favoriteRepo, emailPolicy)
+	categoryService := services.NewCategoryService(categoryRepo)
+	tagService := services.NewTagService(tagRepo)
+	searchService := services.NewSearchService(cfg, searchRepo, tagRepo, searchHistoryRepo)
+	savedSearchService := services.NewSavedSearchService(savedSearchRepo, searchHistoryRepo, searchService)
+	templateService := services.NewTemplateService(promptService, promptRepo)
+	shareService := services.NewShareService(shareLinkRepo, promptRepo, tagRepo, emailPolicy)
+	exportService := services.NewExportService(exportRepo)
+	importService := services.NewImportService(importRepo, importers.Default(), emailPolicy)
 
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.FrontendOrigin},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", handlers.SharePasswordHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -50,21 +139,151 @@ func main() {
 		ctx.String(http.StatusOK, "ok")
 	})
 
+	jwksHandler := handlers.NewJWKSHandler(signingKeys)
+	r.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+
 	api := r.Group("/api")
 
+	// Personal access tokens reach only the routes their scopes cover, and
+	// none of the account settings behind session.
+	session := middleware.RequireSession()
+	read := middleware.RequireScopes(services.ScopePromptsRead)
+	write := middleware.RequireScopes(services.ScopePromptsWrite)
+	execute := middleware.RequireScopes(services.ScopeRunsExecute)
+
 	authHandler := handlers.NewAuthHandler(authService, cfg)
 	api.POST("/auth/register", authHandler.Register)
 	api.POST("/auth/login", authHandler.Login)
 	api.POST("/auth/refresh", authHandler.Refresh)
-	api.POST("/auth/logout", middleware.Authenticate(cfg, authService), authHandler.LogOut)
+	api.POST("/auth/password/forgot", authHandler.ForgotPassword)
+	api.POST("/auth/password/reset", authHandler.ResetPassword)
+	api.POST("/auth/email/verify", authHandler.VerifyEmail)
+	api.POST("/auth/email/resend", middleware.Authenticate(cfg, authService), session, authHandler.ResendVerification)
+	api.POST("/auth/mfa/verify", authHandler.VerifyMFA)
+	api.POST("/auth/logout", middleware.Authenticate(cfg, authService), session, authHandler.LogOut)
 	api.POST("/auth/me", middleware.Authenticate(cfg, authService), authHandler.Me)
 
-	googleHandler := handlers.NewGoogleAuthHandler(authService, cfg)
-	api.GET("/auth/google/start", googleHandler.Start)
-	api.GET("/auth/google/callback", googleHandler.Callback)
+	oauthProviders := oauth.NewRegistry(cfg)
+	oauthHandler := handlers.NewOAuthHandler(authService, identityService, cfg, oauthProviders)
+	api.GET("/auth/providers", oauthHandler.Providers)
+	api.GET("/auth/:provider/start", oauthHandler.Start)
+	api.GET("/auth/:provider/callback", oauthHandler.Callback)
+
+	sessionHandler := handlers.NewSessionHandler(sessionService, cfg)
+	sessions := api.Group("/auth/sessions", middleware.Authenticate(cfg, authService), session)
+	sessions.GET("", sessionHandler.List)
+	sessions.DELETE("", sessionHandler.RevokeOthers)
+	sessions.DELETE("/:id", sessionHandler.Revoke)
+
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	mfa := api.Group("/auth/mfa", middleware.Authenticate(cfg, authService), session)
+	mfa.GET("", mfaHandler.Status)
+	mfa.POST("/enroll", mfaHandler.Enroll)
+	mfa.POST("/confirm", mfaHandler.Confirm)
+	mfa.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	mfa.DELETE("", mfaHandler.Disable)
+
+	passkeyHandler := handlers.NewPasskeyHandler(passkeyService, authService, cfg)
+	api.POST("/auth/passkeys/login/begin", passkeyHandler.BeginLogin)
+	api.POST("/auth/passkeys/login/finish", passkeyHandler.FinishLogin)
+	passkeys := api.Group("/auth/passkeys", middleware.Authenticate(cfg, authService), session)
+	passkeys.GET("", passkeyHandler.List)
+	passkeys.POST("/register/begin", passkeyHandler.BeginRegistration)
+	passkeys.POST("/register/finish", passkeyHandler.FinishRegistration)
+	passkeys.DELETE("/:id", passkeyHandler.Delete)
+
+	identityHandler := handlers.NewIdentityHandler(identityService, oauthProviders, cfg)
+	identities := api.Group("/auth/identities", middleware.Authenticate(cfg, authService), session)
+	identities.GET("", identityHandler.List)
+	identities.POST("/:provider/link", identityHandler.Link)
+	identities.DELETE("/:id", identityHandler.Unlink)
+
+	adminHandler := handlers.NewAdminHandler(roleService)
+	admin := api.Group("/admin", middleware.Authenticate(cfg, authService), session, middleware.RequireRoles(services.RoleAdmin))
+	admin.GET("/roles", adminHandler.ListRoles)
+	admin.GET("/users/:id/roles", adminHandler.UserRoles)
+	admin.PUT("/users/:id/roles/:role", adminHandler.GrantRole)
+	admin.DELETE("/users/:id/roles/:role", adminHandler.RevokeRole)
+
+	personalTokenHandler := handlers.NewPersonalTokenHandler(personalTokenService)
+	tokens := api.Group("/auth/tokens", middleware.Authenticate(cfg, authService), session)
+	tokens.GET("", personalTokenHandler.List)
+	tokens.POST("", personalTokenHandler.Create)
+	tokens.DELETE("/:id", personalTokenHandler.Delete)
 
 	userHandler := handlers.NewUserHandler()
-	api.GET("/user/profile", middleware.Authenticate(cfg, authService), userHandler.Profile)
+	api.GET("/user/profile", middleware.Authenticate(cfg, authService), read, userHandler.Profile)
+
+	promptHandler := handlers.NewPromptHandler(promptService)
+	shareHandler := handlers.NewShareHandler(shareService)
+	prompts := api.Group("/prompts", middleware.Authenticate(cfg, authService))
+	prompts.GET("", read, promptHandler.List)
+	prompts.POST("", write, promptHandler.Create)
+	prompts.GET("/:id", read, promptHandler.Get)
+	prompts.PUT("/:id", write, promptHandler.Update)
+	prompts.DELETE("/:id", write, promptHandler.Delete)
+	prompts.GET("/:id/versions", read, promptHandler.ListVersions)
+	prompts.GET("/:id/versions/:n", read, promptHandler.GetVersion)
+	prompts.POST("/:id/versions/:n/restore", write, promptHandler.Restore)
+	prompts.GET("/:id/diff", read, promptHandler.Diff)
+	prompts.PUT("/:id/tags", write, promptHandler.SetTags)
+	prompts.PUT("/:id/favorite", write, promptHandler.Favorite)
+	prompts.DELETE("/:id/favorite", write, promptHandler.Unfavorite)
+	prompts.GET("/:id/share-links", read, shareHandler.List)
+	prompts.POST("/:id/share-links", write, shareHandler.Create)
+
+	api.DELETE("/share-links/:id", middleware.Authenticate(cfg, authService), write, shareHandler.Revoke)
+	// Share links are the credential; no login is required.
+	api.GET("/shared/:token", shareHandler.Open)
+
+	templateHandler := handlers.NewTemplateHandler(templateService)
+	templates := api.Group("/templates", middleware.Authenticate(cfg, authService))
+	templates.GET("", read, templateHandler.List)
+	templates.POST("/validate", read, templateHandler.Validate)
+	templates.GET("/:id", read, templateHandler.Get)
+	templates.POST("/:id/render", execute, templateHandler.Render)
+
+	categoryHandler := handlers.NewCategoryHandler(categoryService, promptService)
+	categories := api.Group("/categories", middleware.Authenticate(cfg, authService))
+	categories.GET("", read, categoryHandler.List)
+	categories.POST("", write, categoryHandler.Create)
+	categories.PUT("/reorder", write, categoryHandler.Reorder)
+	categories.PATCH("/:id", write, categoryHandler.Rename)
+	categories.POST("/:id/move", write, categoryHandler.Move)
+	categories.DELETE("/:id", write, categoryHandler.Delete)
+	categories.GET("/:id/prompts", read, categoryHandler.Prompts)
+
+	tagHandler := handlers.NewTagHandler(tagService)
+	tags := api.Group("/tags", middleware.Authenticate(cfg, authService))
+	tags.GET("", read, tagHandler.List)
+	tags.GET("/autocomplete", read, tagHandler.Autocomplete)
+	tags.GET("/popular", read, tagHandler.Popular)
+	tags.POST("/merge", write, tagHandler.Merge)
+	tags.PATCH("/:id", write, tagHandler.Rename)
+	tags.DELETE("/:id", write, tagHandler.Delete)
+
+	searchHandler := handlers.NewSearchHandler(searchService)
+	search := api.Group("/search", middleware.Authenticate(cfg, authService))
+	search.GET("", read, searchHandler.Search)
+	search.GET("/history", read, searchHandler.History)
+	search.DELETE("/history", write, searchHandler.ClearHistory)
+	search.DELETE("/history/:id", write, searchHandler.DeleteHistoryEntry)
+
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchService)
+	savedSearches := api.Group("/saved-searches", middleware.Authenticate(cfg, authService))
+	savedSearches.GET("", read, savedSearchHandler.List)
+	savedSearches.POST("", write, savedSearchHandler.Create)
+	savedSearches.GET("/:id", read, savedSearchHandler.Get)
+	savedSearches.PUT("/:id", write, savedSearchHandler.Update)
+	savedSearches.DELETE("/:id", write, savedSearchHandler.Delete)
+	savedSearches.GET("/:id/run", read, savedSearchHandler.Run)
+
+	exportHandler := handlers.NewExportHandler(exportService)
+	api.GET("/export", middleware.Authenticate(cfg, authService), read, exportHandler.Export)
+
+	importHandler := handlers.NewImportHandler(importService)
+	api.POST("/import", middleware.Authenticate(cfg, authService), write, importHandler.Import)
+
 	staticPath := filepath.Join("webapp", "dist")
 	r.Static("/assets", filepath.Join(staticPath, "assets"))
 	r.StaticFile("/favicon.ico", filepath.Join(staticPath, "favicon.ico"))
@@ -77,9 +296,29 @@ func main() {
 		c.File(filepath.Join(staticPath, "index.html"))
 	})
 
-	addr := ":" + cfg.Port
-	log.Printf("API listening on %s", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("server error: %v", err)
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	go func() {
+		log.Printf("API listening on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-stop.Done()
+	log.Print("shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	// Queued mail holds reset and verification links; give it time to go
+	// out before exiting.
+	mailCtx, cancel := context.WithTimeout(context.Background(), mailDrainTimeout)
+	defer cancel()
+	if err := mailQueue.Close(mailCtx); err != nil {
+		log.Printf("mail queue: undelivered mail dropped: %v", err)
 	}
 }
@@ -0,0 +1,131 @@
+// Command keys manages the access token signing keys in JWT_KEYS_DIR.
+//
+//	keys list
+//	keys rotate [-alg EdDSA|RS256] [-stage] [-keep N]
+//	keys activate <kid>
+//	keys remove <kid>
+//
+// rotate creates a key and makes it active. With -stage the key is only
+// published in the JWKS, so verifiers can fetch it before it signs
+// anything; activate it once their caches have caught up. -keep removes
+// all but the newest N keys. Restart the API after any change.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/jwtkeys"
+)
+
+func main() {
+	log.SetFlags(0)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	global := flag.NewFlagSet("keys", flag.ExitOnError)
+	dir := global.String("dir", cfg.JWTKeysDir, "key directory")
+	global.Usage = usage
+	_ = global.Parse(os.Args[1:])
+	if *dir == "" {
+		log.Fatal("set JWT_KEYS_DIR or pass -dir")
+	}
+	args := global.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "list":
+		err = list(*dir)
+	case "rotate":
+		err = rotate(*dir, rest)
+	case "activate":
+		err = withKid(rest, func(kid string) error { return jwtkeys.Activate(*dir, kid) })
+	case "remove":
+		err = withKid(rest, func(kid string) error { return jwtkeys.Remove(*dir, kid) })
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: keys [-dir DIR] list | rotate [-alg EdDSA|RS256] [-stage] [-keep N] | activate KID | remove KID")
+}
+
+func list(dir string) error {
+	s, err := jwtkeys.Load(dir)
+	if err != nil {
+		return err
+	}
+	for _, kid := range s.IDs() {
+		k, _ := s.Lookup(kid)
+		marker := " "
+		if k == s.Active() {
+			marker = "*"
+		}
+		fmt.Printf("%s %s %s\n", marker, kid, k.Alg)
+	}
+	return nil
+}
+
+func rotate(dir string, args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	alg := fs.String("alg", jwtkeys.AlgEdDSA, "signing algorithm: EdDSA or RS256")
+	stage := fs.Bool("stage", false, "publish the key without signing with it")
+	keep := fs.Int("keep", 0, "remove all but the newest N keys (0 keeps all)")
+	_ = fs.Parse(args)
+
+	k, err := jwtkeys.Generate(*alg)
+	if err != nil {
+		return err
+	}
+	if err := jwtkeys.Write(dir, k); err != nil {
+		return err
+	}
+	if *stage {
+		fmt.Printf("staged %s (%s)\n", k.ID, k.Alg)
+	} else {
+		if err := jwtkeys.Activate(dir, k.ID); err != nil {
+			return err
+		}
+		fmt.Printf("activated %s (%s)\n", k.ID, k.Alg)
+	}
+
+	if *keep <= 0 {
+		return nil
+	}
+	s, err := jwtkeys.Load(dir)
+	if err != nil {
+		return err
+	}
+	ids := s.IDs()
+	for i := 0; i < len(ids)-*keep; i++ {
+		if ids[i] == s.Active().ID {
+			continue
+		}
+		if err := jwtkeys.Remove(dir, ids[i]); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s\n", ids[i])
+	}
+	return nil
+}
+
+func withKid(args []string, fn func(string) error) error {
+	if len(args) != 1 {
+		usage()
+		os.Exit(2)
+	}
+	return fn(args[0])
+}
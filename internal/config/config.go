@@ -1,12 +1,22 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	AutoLinkNever    = "never"
+	AutoLinkVerified = "verified"
+
+	RevocationPostgres = "postgres"
+	RevocationMemory   = "memory"
+)
+
 type Config struct {
 	Port                string
 	DBURL               string
@@ -18,9 +28,66 @@ type Config struct {
 	CookieDomain        string
 	CookieSecure        bool
 
+	// JWTKeysDir holds the RS256/EdDSA keys that sign access tokens. When
+	// empty, access tokens are HS256 with JWTAccessSecret.
+	JWTKeysDir string
+	// JWTIssuer, when set, is the iss of every access token and is
+	// required when verifying one.
+	JWTIssuer string
+	// RevocationStore is postgres or memory. memory is only correct with
+	// a single API instance.
+	RevocationStore string
+
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectUrl  string
+
+	GitHubClientID     string
+	GitHubClientSecret string
+	GitHubRedirectURL  string
+
+	// A generic OpenID Connect issuer, served at /api/auth/<OIDCName>.
+	OIDCName         string
+	OIDCDiscoveryURL string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+
+	// OAuthAutoLink is never or verified. With verified, a social login
+	// whose provider vouches for the email joins an existing account that
+	// has also verified it; otherwise the user must link explicitly.
+	OAuthAutoLink string
+
+	// MFAIssuer is the account label shown in authenticator apps.
+	MFAIssuer string
+
+	// WebAuthn relying party. The RP ID defaults to the host of
+	// FrontendOrigin and the allowed origins to FrontendOrigin itself.
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	PasswordResetTTLMinutes int
+
+	EmailVerificationTTLHours      int
+	EmailVerificationResendSeconds int
+	// RequireVerifiedEmail denies unverified users publishing public
+	// prompts and creating share links. They can still log in.
+	RequireVerifiedEmail bool
+
+	SearchHistoryLimit int
+
+	// MailDriver is log, file or smtp.
+	MailDriver      string
+	MailFrom        string
+	MailDir         string
+	MailQueueSize   int
+	MailMaxAttempts int
+	SMTPHost        string
+	SMTPPort        int
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPTLS         bool
 }
 
 func Load() (*Config, error) {
@@ -38,9 +105,60 @@ func Load() (*Config, error) {
 		JWTAccessTTLMinutes: envInt("JWT_ACCESS_TTL_MINUTES", 15),
 		JWTRefreshTTLHrs:    envInt("JWT_REFRESH_TTL_HOURS", 24*7),
 
+		JWTKeysDir: env("JWT_KEYS_DIR", ""),
+		JWTIssuer:  env("JWT_ISSUER", ""),
+
+		RevocationStore: env("REVOCATION_STORE", RevocationPostgres),
+
 		GoogleClientID:     env("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: env("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectUrl:  env("GOOGLE_REDIRECT_URL", ""),
+
+		GitHubClientID:     env("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret: env("GITHUB_CLIENT_SECRET", ""),
+		GitHubRedirectURL:  env("GITHUB_REDIRECT_URL", ""),
+
+		OIDCName:         env("OIDC_NAME", "oidc"),
+		OIDCDiscoveryURL: env("OIDC_DISCOVERY_URL", ""),
+		OIDCClientID:     env("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: env("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  env("OIDC_REDIRECT_URL", ""),
+
+		OAuthAutoLink: env("OAUTH_AUTO_LINK", AutoLinkNever),
+
+		MFAIssuer: env("MFA_ISSUER", "AI Prompt Keeper"),
+
+		WebAuthnRPName: env("WEBAUTHN_RP_NAME", "AI Prompt Keeper"),
+
+		PasswordResetTTLMinutes: envInt("PASSWORD_RESET_TTL_MINUTES", 30),
+
+		EmailVerificationTTLHours:      envInt("EMAIL_VERIFICATION_TTL_HOURS", 48),
+		EmailVerificationResendSeconds: envInt("EMAIL_VERIFICATION_RESEND_SECONDS", 60),
+		RequireVerifiedEmail:           envBool("REQUIRE_VERIFIED_EMAIL", false),
+
+		SearchHistoryLimit: envInt("SEARCH_HISTORY_LIMIT", 50),
+
+		MailDriver:      env("MAIL_DRIVER", "log"),
+		MailFrom:        env("MAIL_FROM", "AI Prompt Keeper <no-reply@localhost>"),
+		MailDir:         env("MAIL_DIR", "tmp/mail"),
+		MailQueueSize:   envInt("MAIL_QUEUE_SIZE", 100),
+		MailMaxAttempts: envInt("MAIL_MAX_ATTEMPTS", 5),
+		SMTPHost:        env("SMTP_HOST", ""),
+		SMTPPort:        envInt("SMTP_PORT", 587),
+		SMTPUsername:    env("SMTP_USERNAME", ""),
+		SMTPPassword:    env("SMTP_PASSWORD", ""),
+		SMTPTLS:         envBool("SMTP_TLS", false),
+	}
+
+	cfg.WebAuthnOrigins = envList("WEBAUTHN_ORIGINS")
+	if len(cfg.WebAuthnOrigins) == 0 && cfg.FrontendOrigin != "" {
+		cfg.WebAuthnOrigins = []string{cfg.FrontendOrigin}
+	}
+	cfg.WebAuthnRPID = env("WEBAUTHN_RP_ID", "")
+	if cfg.WebAuthnRPID == "" {
+		if u, err := url.Parse(cfg.FrontendOrigin); err == nil {
+			cfg.WebAuthnRPID = u.Hostname()
+		}
 	}
 	return cfg, nil
 }
@@ -51,6 +169,18 @@ func env(key, def string) string {
 	}
 	return def
 }
+
+// envList reads a comma-separated list, dropping empty entries.
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func envInt(key string, def int) int {
 	if v := os.Getenv(key); v != "" {
 		if i, err := strconv.Atoi(v); err == nil {
@@ -0,0 +1,167 @@
+// Package diff computes line and word level differences between two texts.
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+type OpType string
+
+const (
+	Equal  OpType = "equal"
+	Insert OpType = "insert"
+	Delete OpType = "delete"
+)
+
+type Op struct {
+	Type OpType `json:"type"`
+	Text string `json:"text"`
+}
+
+// Stats counts changed words.
+type Stats struct {
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+}
+
+// maxCells bounds the LCS table. Inputs whose changed middle section is
+// larger than this are reported as a single replacement.
+const maxCells = 4_000_000
+
+// Lines diffs a and b line by line. Each op's text keeps its trailing newline.
+func Lines(a, b string) []Op {
+	return compute(splitLines(a), splitLines(b))
+}
+
+// Words diffs a and b word by word, keeping whitespace runs as tokens so the
+// ops concatenate back to the original texts.
+func Words(a, b string) []Op {
+	return compute(splitWords(a), splitWords(b))
+}
+
+// Summarize counts the words in inserted and deleted ops. In a line diff a
+// changed line counts all of its words.
+func Summarize(ops []Op) Stats {
+	var s Stats
+	for _, op := range ops {
+		switch op.Type {
+		case Insert:
+			s.Insertions += len(strings.Fields(op.Text))
+		case Delete:
+			s.Deletions += len(strings.Fields(op.Text))
+		}
+	}
+	return s
+}
+
+func compute(a, b []string) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for _, t := range a[:prefix] {
+		ops = appendOp(ops, Equal, t)
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > maxCells {
+		for _, t := range midA {
+			ops = appendOp(ops, Delete, t)
+		}
+		for _, t := range midB {
+			ops = appendOp(ops, Insert, t)
+		}
+	} else {
+		ops = lcs(ops, midA, midB)
+	}
+
+	for _, t := range a[len(a)-suffix:] {
+		ops = appendOp(ops, Equal, t)
+	}
+	return ops
+}
+
+func lcs(ops []Op, a, b []string) []Op {
+	n, m := len(a), len(b)
+	// table[i][j] holds the LCS length of a[i:] and b[j:].
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = appendOp(ops, Equal, a[i])
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = appendOp(ops, Delete, a[i])
+			i++
+		default:
+			ops = appendOp(ops, Insert, b[j])
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = appendOp(ops, Delete, a[i])
+	}
+	for ; j < m; j++ {
+		ops = appendOp(ops, Insert, b[j])
+	}
+	return ops
+}
+
+func appendOp(ops []Op, t OpType, text string) []Op {
+	if n := len(ops); n > 0 && ops[n-1].Type == t {
+		ops[n-1].Text += text
+		return ops
+	}
+	return append(ops, Op{Type: t, Text: text})
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitWords(s string) []string {
+	var tokens []string
+	start := 0
+	prevSpace := false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if i > start && space != prevSpace {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prevSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
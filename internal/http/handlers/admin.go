@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	roles services.RoleService
+}
+
+func NewAdminHandler(roles services.RoleService) *AdminHandler {
+	return &AdminHandler{roles: roles}
+}
+
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roles.List(c.Request.Context())
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *AdminHandler) UserRoles(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	roles, err := h.roles.UserRoles(c.Request.Context(), userID)
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *AdminHandler) GrantRole(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	roles, err := h.roles.Grant(c.Request.Context(), userID, c.Param("role"))
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *AdminHandler) RevokeRole(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	roles, err := h.roles.Revoke(c.Request.Context(), currentUserID(c), userID, c.Param("role"))
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotDemoteSelf):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles"})
+	}
+}
@@ -1,15 +1,24 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
 	"github.com/congdv/go-auth/api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// passwordResetTimeout bounds the background work behind ForgotPassword.
+const passwordResetTimeout = 30 * time.Second
+
 type AuthHandler struct {
 	auth services.AuthService
 	cfg  *config.Config
@@ -51,9 +60,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	auth, access, refresh, _, refreshExp, err := h.auth.Login(c.Request.Context(), req.Email, req.Password)
+	auth, access, refresh, _, refreshExp, err := h.auth.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c))
+	var mfa *services.MFARequiredError
+	if errors.As(err, &mfa) {
+		writeMFARequired(c, mfa)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "email or password is invalid"})
+		return
 	}
 	setRefreshCookie(c, h.cfg, refresh, refreshExp)
 	c.JSON(http.StatusOK, gin.H{
@@ -63,6 +78,47 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// writeMFARequired answers the first login step for accounts with MFA. No
+// tokens are issued until VerifyMFA.
+func writeMFARequired(c *gin.Context, mfa *services.MFARequiredError) {
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":   true,
+		"mfa_token":      mfa.Token,
+		"mfa_expires_at": mfa.ExpiresAt,
+	})
+}
+
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token and code are required"})
+		return
+	}
+
+	auth, access, refresh, _, refreshExp, err := h.auth.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+	setRefreshCookie(c, h.cfg, refresh, refreshExp)
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": access,
+		"user":         auth.User,
+		"roles":        auth.Roles,
+	})
+}
+
+// clientInfo identifies the device a session is issued to.
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
 func setRefreshCookie(c *gin.Context, cfg *config.Config, value string, exp time.Time) {
 	httpOnlyRefreshCookie(c, cfg, value, exp)
 }
@@ -79,59 +135,130 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	userID, jti, _, err := h.auth.ValidateRefreshToken(cookie)
+	auth, access, refresh, _, refreshExp, err := h.auth.Refresh(c.Request.Context(), cookie, clientInfo(c))
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
 		return
 	}
 
-	rt, err := h.auth.GetFreshByJTI(c.Request.Context(), jti)
-	if err != nil || rt.IsRevoked || time.Now().After(rt.ExpiresAt) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token revoked or expired"})
+	setRefreshCookie(c, h.cfg, refresh, refreshExp)
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": access,
+		"user":         auth.User,
+		"roles":        auth.Roles,
+	})
+}
+
+// LogOut ends the session on this device: the refresh cookie and the
+// access token used to call it both stop working.
+func (h *AuthHandler) LogOut(c *gin.Context) {
+	if claims, ok := c.Get("accessClaims"); ok {
+		if err := h.auth.RevokeAccessToken(c.Request.Context(), claims.(services.AccessClaims)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+			return
+		}
+	}
+	if cookie, err := c.Cookie("refresh_token"); err == nil && cookie != "" {
+		if _, jti, _, err := h.auth.ValidateRefreshToken(cookie); err == nil {
+			_ = h.auth.RevokeRefresh(c.Request.Context(), jti)
+		}
+		httpOnlyRefreshCookie(c, h.cfg, "", time.Unix(0, 0))
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword always answers 202 straight away. The reset is handled in
+// the background so the response time does not reveal whether the account
+// exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
 		return
 	}
 
-	_ = h.auth.RevokeRefresh(c.Request.Context(), jti)
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, passwordResetTimeout)
+		defer cancel()
+		if err := h.auth.RequestPasswordReset(ctx, req.Email); err != nil {
+			log.Printf("password reset request failed: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset link has been sent"})
+}
 
-	auth, err := h.auth.Me(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token or password"})
 		return
 	}
 
-	access, _, err := h.auth.GenerateAccessToken(auth.User, auth.Roles)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue access token"})
+	if err := h.auth.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
 		return
 	}
 
-	refresh, newJTI, refreshExp, err := h.auth.GenerateFreshToken(auth.User)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue refresh token"})
+	httpOnlyRefreshCookie(c, h.cfg, "", time.Unix(0, 0))
+	c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
 		return
 	}
 
-	if err := h.auth.SaveRefresh(c.Request.Context(), auth.User.ID, newJTI, refreshExp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save refresh token"})
+	auth, err := h.auth.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
 		return
 	}
 
-	setRefreshCookie(c, h.cfg, refresh, refreshExp)
-	c.JSON(http.StatusOK, gin.H{
-		"access_token": access,
-		"user":         auth.User,
-		"roles":        auth.Roles,
-	})
+	c.JSON(http.StatusOK, gin.H{"user": auth.User, "roles": auth.Roles})
 }
 
-func (h *AuthHandler) LogOut(c *gin.Context) {
-	if cookie, err := c.Cookie("refresh_token"); err == nil && cookie != "" {
-		if _, jti, _, err := h.auth.ValidateRefreshToken(cookie); err != nil {
-			_ = h.auth.RevokeRefresh(c.Request.Context(), jti)
-		}
-
-		httpOnlyRefreshCookie(c, h.cfg, "", time.Unix(0, 0))
-		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	err := h.auth.ResendVerification(c.Request.Context(), currentUserID(c))
+	var throttled *services.ThrottledError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send verification email"})
 	}
 }
 
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/oauth"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oauthStateTTL = 5 * time.Minute
+	// oauthFlowCookie carries the state, PKCE verifier and nonce between
+	// Start and Callback.
+	oauthFlowCookie = "oauth_flow"
+)
+
+// OAuthHandler runs the authorization code flow for every registered
+// provider under /api/auth/:provider.
+type OAuthHandler struct {
+	auth       services.AuthService
+	identities services.IdentityService
+	cfg        *config.Config
+	providers  *oauth.Registry
+}
+
+func NewOAuthHandler(auth services.AuthService, identities services.IdentityService, cfg *config.Config, providers *oauth.Registry) *OAuthHandler {
+	return &OAuthHandler{auth: auth, identities: identities, cfg: cfg, providers: providers}
+}
+
+// oauthFlowState is the oauth_flow cookie. Link is set when the flow links
+// a provider to a signed-in account instead of logging in. It only ever
+// comes from the cookie IdentityHandler.Link sets, never from the URL, so a
+// link started by one browser cannot be finished by another.
+type oauthFlowState struct {
+	oauth.Flow
+	Link string `json:"l,omitempty"`
+}
+
+// Providers lists the configured provider names so the UI knows which
+// buttons to show.
+func (h *OAuthHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.providers.Names()})
+}
+
+func (h *OAuthHandler) provider(c *gin.Context) (oauth.Provider, bool) {
+	p, ok := h.providers.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oauth provider not configured"})
+	}
+	return p, ok
+}
+
+func (h *OAuthHandler) Start(c *gin.Context) {
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	flow := oauthFlowState{Flow: oauth.NewFlow()}
+	if pending, ok := decodeOAuthFlow(c); ok && pending.Link != "" {
+		provider, err := h.identities.LinkIntentProvider(c.Request.Context(), pending.Link)
+		if err != nil || provider != p.Name() {
+			clearOAuthFlow(c, h.cfg, p.Name())
+			c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidLinkRequest.Error()})
+			return
+		}
+		flow.Link = pending.Link
+	}
+
+	url, err := p.AuthCodeURL(c.Request.Context(), flow.Flow)
+	if err != nil {
+		log.Printf("oauth %s: %v", p.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "oauth provider unavailable"})
+		return
+	}
+
+	setOAuthFlow(c, h.cfg, p.Name(), flow)
+	c.Redirect(http.StatusFound, url)
+}
+
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	flow, ok := readOAuthFlow(c)
+	stateParam := c.Query("state")
+	if !ok || stateParam == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(stateParam)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+		return
+	}
+	// The flow is single use.
+	clearOAuthFlow(c, h.cfg, p.Name())
+
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "oauth sign-in was cancelled or denied"})
+		return
+	}
+
+	id, err := p.Exchange(c.Request.Context(), c.Query("code"), flow.Flow)
+	if errors.Is(err, oauth.ErrNoVerifiedEmail) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, oauth.ErrInvalidIDToken) {
+		log.Printf("oauth %s: %v", p.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "oauth identity could not be verified"})
+		return
+	}
+	if err != nil {
+		log.Printf("oauth %s: %v", p.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "oauth exchange failed"})
+		return
+	}
+
+	ext := models.ExternalIdentity{
+		Provider:      id.Provider,
+		Subject:       id.Subject,
+		Email:         id.Email,
+		EmailVerified: id.EmailVerified,
+	}
+
+	if flow.Link != "" {
+		if _, err := h.identities.CompleteLink(c.Request.Context(), flow.Link, ext); err != nil {
+			writeIdentityError(c, err)
+			return
+		}
+		c.Redirect(http.StatusFound, h.cfg.FrontendOrigin+"/dashboard?linked="+p.Name())
+		return
+	}
+
+	auth, err := h.auth.OAuthLogin(c.Request.Context(), ext)
+	if errors.Is(err, services.ErrIdentityLinkRequired) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrIdentityEmailUnverified) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process user"})
+		return
+	}
+	// A social login is only the first factor for accounts with MFA.
+	var mfa *services.MFARequiredError
+	if err := h.auth.RequireMFA(c.Request.Context(), auth.User.ID); errors.As(err, &mfa) {
+		c.Redirect(http.StatusFound, h.cfg.FrontendOrigin+"/oauth/callback?mfa_token="+mfa.Token)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process user"})
+		return
+	}
+
+	_, accessToken, refresh, _, refreshExp, err := h.auth.StartSession(c.Request.Context(), auth.User.ID, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue tokens"})
+		return
+	}
+
+	// Set refresh cookie (path limited to /api/auth)
+	httpOnlyRefreshCookie(c, h.cfg, refresh, refreshExp)
+	url := h.cfg.FrontendOrigin + "/oauth/callback?token=" + accessToken
+	c.Redirect(http.StatusFound, url)
+}
+
+// setOAuthFlow scopes the cookie to the provider's routes.
+func setOAuthFlow(c *gin.Context, cfg *config.Config, provider string, flow oauthFlowState) {
+	b, _ := json.Marshal(flow)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthFlowCookie, base64.RawURLEncoding.EncodeToString(b), int(oauthStateTTL.Seconds()), "/api/auth/"+provider, cfg.CookieDomain, cfg.CookieSecure, true)
+}
+
+func clearOAuthFlow(c *gin.Context, cfg *config.Config, provider string) {
+	c.SetCookie(oauthFlowCookie, "", -1, "/api/auth/"+provider, cfg.CookieDomain, cfg.CookieSecure, true)
+}
+
+// readOAuthFlow returns a flow started by Start.
+func readOAuthFlow(c *gin.Context) (oauthFlowState, bool) {
+	flow, ok := decodeOAuthFlow(c)
+	return flow, ok && flow.State != "" && flow.Verifier != ""
+}
+
+func decodeOAuthFlow(c *gin.Context) (oauthFlowState, bool) {
+	var flow oauthFlowState
+	v, err := c.Cookie(oauthFlowCookie)
+	if err != nil || v == "" {
+		return flow, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil || json.Unmarshal(b, &flow) != nil {
+		return flow, false
+	}
+	return flow, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CategoryHandler struct {
+	categories services.CategoryService
+	prompts    services.PromptService
+}
+
+func NewCategoryHandler(categories services.CategoryService, prompts services.PromptService) *CategoryHandler {
+	return &CategoryHandler{categories: categories, prompts: prompts}
+}
+
+func (h *CategoryHandler) List(c *gin.Context) {
+	tree, err := h.categories.Tree(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
+type createCategoryRequest struct {
+	Name     string        `json:"name" binding:"required"`
+	ParentID uuid.NullUUID `json:"parent_id"`
+}
+
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var req createCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+		return
+	}
+
+	cat, err := h.categories.Create(c.Request.Context(), currentUserID(c), req.ParentID, req.Name)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"category": cat})
+}
+
+type renameCategoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func (h *CategoryHandler) Rename(c *gin.Context) {
+	id, ok := categoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req renameCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+		return
+	}
+
+	cat, err := h.categories.Rename(c.Request.Context(), currentUserID(c), id, req.Name)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"category": cat})
+}
+
+type moveCategoryRequest struct {
+	ParentID uuid.NullUUID `json:"parent_id"`
+	Position *int          `json:"position"`
+}
+
+func (h *CategoryHandler) Move(c *gin.Context) {
+	id, ok := categoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req moveCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid move"})
+		return
+	}
+	position := -1
+	if req.Position != nil {
+		position = *req.Position
+	}
+
+	cat, err := h.categories.Move(c.Request.Context(), currentUserID(c), id, req.ParentID, position)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"category": cat})
+}
+
+type reorderCategoriesRequest struct {
+	ParentID uuid.NullUUID `json:"parent_id"`
+	IDs      []uuid.UUID   `json:"ids" binding:"required"`
+}
+
+func (h *CategoryHandler) Reorder(c *gin.Context) {
+	var req reorderCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reorder"})
+		return
+	}
+
+	if err := h.categories.Reorder(c.Request.Context(), currentUserID(c), req.ParentID, req.IDs); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CategoryHandler) Delete(c *gin.Context) {
+	id, ok := categoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.categories.Delete(c.Request.Context(), currentUserID(c), id, c.Query("strategy")); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CategoryHandler) Prompts(c *gin.Context) {
+	id, ok := categoryIDParam(c)
+	if !ok {
+		return
+	}
+
+	params, ok := listParams(c)
+	if !ok {
+		return
+	}
+	params.CategoryID = uuid.NullUUID{UUID: id, Valid: true}
+
+	prompts, total, err := h.prompts.List(c.Request.Context(), currentUserID(c), params)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts, "total": total})
+}
+
+func categoryIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCategoryExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCategory), errors.Is(err, services.ErrCategoryCycle),
+		errors.Is(err, services.ErrInvalidCategoryOrder), errors.Is(err, services.ErrInvalidDeleteStrategy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process category"})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exports services.ExportService
+}
+
+func NewExportHandler(exports services.ExportService) *ExportHandler {
+	return &ExportHandler{exports: exports}
+}
+
+var exportFiles = map[string]struct {
+	contentType string
+	extension   string
+}{
+	services.ExportJSON:     {"application/json", ".json"},
+	services.ExportMarkdown: {"application/zip", ".zip"},
+	services.ExportZip:      {"application/zip", ".zip"},
+}
+
+func (h *ExportHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", services.ExportJSON)
+	file, ok := exportFiles[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidExportFormat.Error()})
+		return
+	}
+
+	name := "keeperprompt-" + format + "-" + time.Now().UTC().Format("20060102") + file.extension
+	c.Header("Content-Type", file.contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Cache-Control", "no-store")
+
+	err := h.exports.Export(c.Request.Context(), currentUserID(c), format, c.Writer)
+	if err == nil {
+		return
+	}
+	// Once streaming has started the status is already sent; all that is
+	// left is to cut the response short.
+	if c.Writer.Written() {
+		log.Printf("export failed mid-stream: %v", err)
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	if errors.Is(err, services.ErrInvalidExportFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export library"})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/oauth"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// IdentityHandler manages the social identities linked to the signed-in
+// account.
+type IdentityHandler struct {
+	identities services.IdentityService
+	providers  *oauth.Registry
+	cfg        *config.Config
+}
+
+func NewIdentityHandler(identities services.IdentityService, providers *oauth.Registry, cfg *config.Config) *IdentityHandler {
+	return &IdentityHandler{identities: identities, providers: providers, cfg: cfg}
+}
+
+func (h *IdentityHandler) List(c *gin.Context) {
+	methods, err := h.identities.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		writeIdentityError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, methods)
+}
+
+type linkIdentityRequest struct {
+	Password string `json:"password"`
+}
+
+// Link re-authenticates the user, stores the link intent in this browser's
+// oauth_flow cookie and returns the URL that starts the provider flow. The
+// same browser must navigate to it; the intent never appears in a URL.
+func (h *IdentityHandler) Link(c *gin.Context) {
+	provider := c.Param("provider")
+	if _, ok := h.providers.Get(provider); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "oauth provider not configured"})
+		return
+	}
+	var req linkIdentityRequest
+	// The body is optional for accounts without a password.
+	_ = c.ShouldBindJSON(&req)
+
+	intent, err := h.identities.BeginLink(c.Request.Context(), currentUserID(c), provider, req.Password)
+	if err != nil {
+		writeIdentityError(c, err)
+		return
+	}
+	setOAuthFlow(c, h.cfg, provider, oauthFlowState{Link: intent})
+	c.JSON(http.StatusOK, gin.H{"url": "/api/auth/" + provider + "/start"})
+}
+
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid identity id"})
+		return
+	}
+	if err := h.identities.Unlink(c.Request.Context(), currentUserID(c), id); err != nil {
+		writeIdentityError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeIdentityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrIdentityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrIdentityInUse), errors.Is(err, services.ErrLastLoginMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReauthRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidLinkRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process identity"})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBytes caps the whole upload; imports are parsed in memory.
+const maxImportBytes = 32 << 20
+
+type ImportHandler struct {
+	imports services.ImportService
+}
+
+func NewImportHandler(imports services.ImportService) *ImportHandler {
+	return &ImportHandler{imports: imports}
+}
+
+// Import accepts one or more multipart "file" fields, or a library document
+// posted directly as JSON. Options come from the query string or form:
+// source, strategy and dry_run.
+func (h *ImportHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	files, err := importFiles(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.PostForm("dry_run")))
+	opts := models.ImportOptions{
+		Source:   c.DefaultQuery("source", c.PostForm("source")),
+		Strategy: c.DefaultQuery("strategy", c.PostForm("strategy")),
+		DryRun:   dryRun,
+	}
+
+	report, err := h.imports.Import(c.Request.Context(), currentUserID(c), files, opts)
+	if err != nil {
+		writeImportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+func importFiles(c *gin.Context) ([]models.ImportFile, error) {
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		return []models.ImportFile{{Name: "library.json", Data: data}}, nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	headers := form.File["file"]
+	if len(headers) == 0 {
+		return nil, errors.New("no files uploaded")
+	}
+
+	files := make([]models.ImportFile, 0, len(headers))
+	for _, fh := range headers {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, models.ImportFile{Name: fh.Filename, Data: data})
+	}
+	return files, nil
+}
+
+func writeImportError(c *gin.Context, err error) {
+	var importErr *models.ImportError
+	switch {
+	case errors.As(err, &importErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid import", "issues": importErr.Issues})
+	case errors.Is(err, services.ErrInvalidImportStrategy), errors.Is(err, services.ErrUnknownImportSource):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import prompts"})
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/jwtkeys"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the access token verification keys so other
+// services can check keeper tokens without a shared secret.
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS serves an empty set when tokens are still HS256.
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	set := jwtkeys.JWKS{Keys: []jwtkeys.JWK{}}
+	if h.keys != nil {
+		set = h.keys.JWKS()
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
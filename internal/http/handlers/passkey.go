@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PasskeyHandler serves the WebAuthn ceremonies. Each begin call returns the
+// options to hand to navigator.credentials and a session token; the finish
+// call sends both back with the browser's response.
+type PasskeyHandler struct {
+	passkeys services.PasskeyService
+	auth     services.AuthService
+	cfg      *config.Config
+}
+
+func NewPasskeyHandler(passkeys services.PasskeyService, auth services.AuthService, cfg *config.Config) *PasskeyHandler {
+	return &PasskeyHandler{passkeys: passkeys, auth: auth, cfg: cfg}
+}
+
+type finishPasskeyRequest struct {
+	Session    string          `json:"session" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+	Name       string          `json:"name"`
+}
+
+func (h *PasskeyHandler) List(c *gin.Context) {
+	passkeys, err := h.passkeys.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"passkeys": passkeys})
+}
+
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	session, options, err := h.passkeys.BeginRegistration(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session": session, "options": options})
+}
+
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	var req finishPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session and credential are required"})
+		return
+	}
+	passkey, err := h.passkeys.FinishRegistration(c.Request.Context(), currentUserID(c), req.Session, req.Name, req.Credential)
+	if err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, passkey)
+}
+
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid passkey id"})
+		return
+	}
+	if err := h.passkeys.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	session, options, err := h.passkeys.BeginLogin(c.Request.Context())
+	if err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session": session, "options": options})
+}
+
+// FinishLogin answers like Login: tokens, or an MFA challenge when the
+// authenticator did not verify the user and the account has MFA.
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var req finishPasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session and credential are required"})
+		return
+	}
+	login, err := h.passkeys.FinishLogin(c.Request.Context(), req.Session, req.Credential, clientInfo(c))
+	if err != nil {
+		writePasskeyError(c, err)
+		return
+	}
+
+	auth, access, refresh, _, refreshExp, err := h.auth.LoginWithPasskey(c.Request.Context(), login, clientInfo(c))
+	var mfa *services.MFARequiredError
+	if errors.As(err, &mfa) {
+		writeMFARequired(c, mfa)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign in"})
+		return
+	}
+	setRefreshCookie(c, h.cfg, refresh, refreshExp)
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": access,
+		"user":         auth.User,
+		"roles":        auth.Roles,
+	})
+}
+
+func writePasskeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPasskeysDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPasskeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPasskeyInUse), errors.Is(err, services.ErrLastLoginMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPasskeyCeremony):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPasskeyVerificationFail):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process passkey"})
+	}
+}
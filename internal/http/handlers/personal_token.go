@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PersonalTokenHandler manages the API tokens users create for scripts and
+// the CLI.
+type PersonalTokenHandler struct {
+	tokens services.PersonalTokenService
+}
+
+func NewPersonalTokenHandler(tokens services.PersonalTokenService) *PersonalTokenHandler {
+	return &PersonalTokenHandler{tokens: tokens}
+}
+
+type createPersonalTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (h *PersonalTokenHandler) List(c *gin.Context) {
+	tokens, err := h.tokens.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		writePersonalTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// Create returns the token itself once; only its prefix is kept.
+func (h *PersonalTokenHandler) Create(c *gin.Context) {
+	var req createPersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and scopes are required"})
+		return
+	}
+	t, raw, err := h.tokens.Create(c.Request.Context(), currentUserID(c), req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		writePersonalTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": raw, "personal_token": t})
+}
+
+func (h *PersonalTokenHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+	if err := h.tokens.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		writePersonalTokenError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writePersonalTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPersonalTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTokenName),
+		errors.Is(err, services.ErrInvalidScope),
+		errors.Is(err, services.ErrInvalidTokenExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to manage tokens"})
+	}
+}
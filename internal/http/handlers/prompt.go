@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/congdv/go-auth/api/internal/templating"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PromptHandler struct {
+	prompts services.PromptService
+}
+
+func NewPromptHandler(prompts services.PromptService) *PromptHandler {
+	return &PromptHandler{prompts: prompts}
+}
+
+type promptRequest struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+	Body        string `json:"body" binding:"required"`
+	Model       string `json:"model" binding:"max=100"`
+	ChangeNote  string `json:"change_note" binding:"max=500"`
+	Visibility  string `json:"visibility"`
+
+	Variables  models.TemplateVariables `json:"variables"`
+	CategoryID optionalUUID             `json:"category_id"`
+	Tags       []string                 `json:"tags"`
+}
+
+func (r promptRequest) input() models.PromptInput {
+	in := models.PromptInput{
+		Title:       r.Title,
+		Description: r.Description,
+		Body:        r.Body,
+		Model:       r.Model,
+		Variables:   r.Variables,
+		ChangeNote:  r.ChangeNote,
+		Visibility:  r.Visibility,
+		Tags:        r.Tags,
+	}
+	if r.CategoryID.Set {
+		in.CategoryID = &r.CategoryID.Value
+	}
+	return in
+}
+
+// optionalUUID tells an omitted field apart from an explicit null, which
+// uuid.NullUUID alone decodes the same way.
+type optionalUUID struct {
+	Set   bool
+	Value uuid.NullUUID
+}
+
+func (o *optionalUUID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (h *PromptHandler) Create(c *gin.Context) {
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prompt"})
+		return
+	}
+
+	p, err := h.prompts.Create(c.Request.Context(), currentUserID(c), req.input())
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"prompt": p})
+}
+
+func (h *PromptHandler) Get(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	p, err := h.prompts.Get(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": p})
+}
+
+func (h *PromptHandler) List(c *gin.Context) {
+	params, ok := listParams(c)
+	if !ok {
+		return
+	}
+
+	prompts, total, err := h.prompts.List(c.Request.Context(), currentUserID(c), params)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompts": prompts, "total": total})
+}
+
+func (h *PromptHandler) Update(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prompt"})
+		return
+	}
+
+	p, err := h.prompts.Update(c.Request.Context(), currentUserID(c), id, req.input())
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": p})
+}
+
+func (h *PromptHandler) Delete(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.prompts.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PromptHandler) ListVersions(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.prompts.ListVersions(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func (h *PromptHandler) GetVersion(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+	n, ok := versionParam(c)
+	if !ok {
+		return
+	}
+
+	v, err := h.prompts.GetVersion(c.Request.Context(), currentUserID(c), id, n)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"version": v})
+}
+
+func (h *PromptHandler) Diff(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to versions are required"})
+		return
+	}
+
+	d, err := h.prompts.Diff(c.Request.Context(), currentUserID(c), id, from, to, c.Query("mode"))
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": d})
+}
+
+func (h *PromptHandler) Restore(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+	n, ok := versionParam(c)
+	if !ok {
+		return
+	}
+
+	p, err := h.prompts.Restore(c.Request.Context(), currentUserID(c), id, n)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": p})
+}
+
+type setTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+func (h *PromptHandler) SetTags(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req setTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tags"})
+		return
+	}
+
+	p, err := h.prompts.SetTags(c.Request.Context(), currentUserID(c), id, req.Tags)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": p})
+}
+
+func (h *PromptHandler) Favorite(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	p, err := h.prompts.Favorite(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": p})
+}
+
+func (h *PromptHandler) Unfavorite(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.prompts.Unfavorite(c.Request.Context(), currentUserID(c), id); err != nil {
+		writePromptError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func listParams(c *gin.Context) (models.PromptListParams, bool) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	descendants, _ := strconv.ParseBool(c.DefaultQuery("include_descendants", "false"))
+	favorites, _ := strconv.ParseBool(c.DefaultQuery("favorites", "false"))
+	params := models.PromptListParams{
+		Limit:              limit,
+		Offset:             offset,
+		IncludeDescendants: descendants,
+		FavoritesOnly:      favorites,
+	}
+
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			return models.PromptListParams{}, false
+		}
+		params.CategoryID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if raw := c.Query("saved_search"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search id"})
+			return models.PromptListParams{}, false
+		}
+		params.SavedSearchID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return params, true
+}
+
+func currentUserID(c *gin.Context) uuid.UUID {
+	uidVal, _ := c.Get("userId")
+	userID, _ := uidVal.(uuid.UUID)
+	return userID
+}
+
+func promptIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prompt id"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func versionParam(c *gin.Context) (int, bool) {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return 0, false
+	}
+	return n, true
+}
+
+func writePromptError(c *gin.Context, err error) {
+	var tmplErr *templating.Error
+	switch {
+	case errors.As(err, &tmplErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid template", "issues": tmplErr.Issues})
+	case errors.Is(err, services.ErrPromptNotFound), errors.Is(err, services.ErrVersionNotFound),
+		errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPrompt), errors.Is(err, services.ErrInvalidDiffMode),
+		errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrTooManyTags),
+		errors.Is(err, services.ErrInvalidVisibility):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process prompt"})
+	}
+}
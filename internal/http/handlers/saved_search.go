@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SavedSearchHandler struct {
+	saved services.SavedSearchService
+}
+
+func NewSavedSearchHandler(saved services.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{saved: saved}
+}
+
+func (h *SavedSearchHandler) List(c *gin.Context) {
+	searches, err := h.saved.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_searches": searches})
+}
+
+func (h *SavedSearchHandler) Get(c *gin.Context) {
+	id, ok := savedSearchIDParam(c)
+	if !ok {
+		return
+	}
+
+	saved, err := h.saved.Get(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_search": saved})
+}
+
+// savedSearchRequest carries either explicit filters or the id of a
+// history entry to pin.
+type savedSearchRequest struct {
+	Name      string               `json:"name" binding:"required"`
+	Filters   models.SearchFilters `json:"filters"`
+	HistoryID uuid.NullUUID        `json:"history_id"`
+}
+
+func (h *SavedSearchHandler) Create(c *gin.Context) {
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search"})
+		return
+	}
+
+	var (
+		saved models.SavedSearch
+		err   error
+	)
+	if req.HistoryID.Valid {
+		saved, err = h.saved.CreateFromHistory(c.Request.Context(), currentUserID(c), req.Name, req.HistoryID.UUID)
+	} else {
+		saved, err = h.saved.Create(c.Request.Context(), currentUserID(c), req.Name, req.Filters)
+	}
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"saved_search": saved})
+}
+
+func (h *SavedSearchHandler) Update(c *gin.Context) {
+	id, ok := savedSearchIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search"})
+		return
+	}
+
+	saved, err := h.saved.Update(c.Request.Context(), currentUserID(c), id, req.Name, req.Filters)
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_search": saved})
+}
+
+func (h *SavedSearchHandler) Delete(c *gin.Context) {
+	id, ok := savedSearchIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.saved.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SavedSearchHandler) Run(c *gin.Context) {
+	id, ok := savedSearchIDParam(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	results, next, err := h.saved.Run(c.Request.Context(), currentUserID(c), id, limit, c.Query("cursor"))
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "next_cursor": next})
+}
+
+func savedSearchIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search id"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
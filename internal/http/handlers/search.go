@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SearchHandler struct {
+	search services.SearchService
+}
+
+func NewSearchHandler(search services.SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+func (h *SearchHandler) Search(c *gin.Context) {
+	filters, err := searchFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	results, next, err := h.search.Search(c.Request.Context(), currentUserID(c), filters, limit, c.Query("cursor"))
+	if err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "next_cursor": next})
+}
+
+func (h *SearchHandler) History(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entries, err := h.search.History(c.Request.Context(), currentUserID(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load search history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries})
+}
+
+func (h *SearchHandler) DeleteHistoryEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid history id"})
+		return
+	}
+
+	if err := h.search.DeleteHistoryEntry(c.Request.Context(), currentUserID(c), id); err != nil {
+		writeSearchError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SearchHandler) ClearHistory(c *gin.Context) {
+	if err := h.search.ClearHistory(c.Request.Context(), currentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear search history"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// searchFiltersFromQuery reads filters from the query string. Tags may be
+// repeated (?tag=a&tag=b) or comma separated (?tags=a,b); dates accept
+// RFC 3339 timestamps or plain YYYY-MM-DD days.
+func searchFiltersFromQuery(c *gin.Context) (models.SearchFilters, error) {
+	f := models.SearchFilters{
+		Query:      c.Query("q"),
+		Model:      c.Query("model"),
+		Visibility: c.Query("visibility"),
+		Tags:       c.QueryArray("tag"),
+	}
+	if raw := c.Query("tags"); raw != "" {
+		f.Tags = append(f.Tags, strings.Split(raw, ",")...)
+	}
+
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return models.SearchFilters{}, errors.New("invalid category id")
+		}
+		f.CategoryID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if raw := c.Query("favorite"); raw != "" {
+		favorite, err := strconv.ParseBool(raw)
+		if err != nil {
+			return models.SearchFilters{}, errors.New("invalid favorite flag")
+		}
+		f.Favorite = favorite
+	}
+
+	var err error
+	if f.CreatedAfter, err = parseDateParam(c, "created_after"); err != nil {
+		return models.SearchFilters{}, err
+	}
+	if f.CreatedBefore, err = parseDateParam(c, "created_before"); err != nil {
+		return models.SearchFilters{}, err
+	}
+	return f, nil
+}
+
+func parseDateParam(c *gin.Context, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s", key)
+}
+
+func writeSearchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSavedSearchNotFound), errors.Is(err, services.ErrHistoryEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSavedSearchExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCursor), errors.Is(err, services.ErrInvalidDateRange),
+		errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrTooManyTags),
+		errors.Is(err, services.ErrInvalidSavedSearch), errors.Is(err, services.ErrInvalidVisibility):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	sessions services.SessionService
+	cfg      *config.Config
+}
+
+func NewSessionHandler(sessions services.SessionService, cfg *config.Config) *SessionHandler {
+	return &SessionHandler{sessions: sessions, cfg: cfg}
+}
+
+func (h *SessionHandler) List(c *gin.Context) {
+	sessions, err := h.sessions.List(c.Request.Context(), currentUserID(c), currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// Revoke signs out one session. Revoking the current one also clears the
+// refresh cookie, like LogOut.
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+	err = h.sessions.Revoke(c.Request.Context(), currentUserID(c), id)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if id == currentSessionID(c) {
+		httpOnlyRefreshCookie(c, h.cfg, "", time.Unix(0, 0))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOthers is "log out everywhere except this device".
+func (h *SessionHandler) RevokeOthers(c *gin.Context) {
+	n, err := h.sessions.RevokeOthers(c.Request.Context(), currentUserID(c), currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
+
+// currentSessionID is the session of the access token, or uuid.Nil for
+// tokens issued before sessions were tracked.
+func currentSessionID(c *gin.Context) uuid.UUID {
+	v, _ := c.Get("sessionId")
+	id, _ := v.(uuid.UUID)
+	return id
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SharePasswordHeader carries the password for protected share links so it
+// stays out of URLs and access logs.
+const SharePasswordHeader = "X-Share-Password"
+
+type ShareHandler struct {
+	shares services.ShareService
+}
+
+func NewShareHandler(shares services.ShareService) *ShareHandler {
+	return &ShareHandler{shares: shares}
+}
+
+type createShareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Password  string     `json:"password" binding:"max=200"`
+}
+
+func (h *ShareHandler) Create(c *gin.Context) {
+	promptID, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req createShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share link"})
+		return
+	}
+
+	link, err := h.shares.CreateLink(c.Request.Context(), currentUserID(c), promptID, models.ShareLinkInput{
+		ExpiresAt: req.ExpiresAt,
+		Password:  req.Password,
+	})
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"share_link": link})
+}
+
+func (h *ShareHandler) List(c *gin.Context) {
+	promptID, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.shares.ListLinks(c.Request.Context(), currentUserID(c), promptID)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"share_links": links})
+}
+
+func (h *ShareHandler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share link id"})
+		return
+	}
+
+	link, err := h.shares.RevokeLink(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"share_link": link})
+}
+
+// Open serves a shared prompt without authentication.
+func (h *ShareHandler) Open(c *gin.Context) {
+	prompt, err := h.shares.Open(c.Request.Context(), c.Param("token"), c.GetHeader(SharePasswordHeader))
+	c.Header("Cache-Control", "no-store")
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prompt": prompt})
+}
+
+func writeShareError(c *gin.Context, err error) {
+	var throttled *services.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPromptNotFound), errors.Is(err, services.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShareLinkExpired), errors.Is(err, services.ErrShareLinkRevoked):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSharePasswordRequired), errors.Is(err, services.ErrSharePasswordInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidShareExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process share link"})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TagHandler struct {
+	tags services.TagService
+}
+
+func NewTagHandler(tags services.TagService) *TagHandler {
+	return &TagHandler{tags: tags}
+}
+
+func (h *TagHandler) List(c *gin.Context) {
+	tags, err := h.tags.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func (h *TagHandler) Autocomplete(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	tags, err := h.tags.Autocomplete(c.Request.Context(), currentUserID(c), c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to autocomplete tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func (h *TagHandler) Popular(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	tags, err := h.tags.Popular(c.Request.Context(), currentUserID(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load popular tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+type renameTagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func (h *TagHandler) Rename(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return
+	}
+
+	var req renameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag"})
+		return
+	}
+
+	tag, err := h.tags.Rename(c.Request.Context(), currentUserID(c), id, req.Name)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+type mergeTagsRequest struct {
+	TargetID  uuid.UUID   `json:"target_id" binding:"required"`
+	SourceIDs []uuid.UUID `json:"source_ids" binding:"required,min=1"`
+}
+
+func (h *TagHandler) Merge(c *gin.Context) {
+	var req mergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid merge"})
+		return
+	}
+
+	tag, err := h.tags.Merge(c.Request.Context(), currentUserID(c), req.TargetID, req.SourceIDs)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+func (h *TagHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		return
+	}
+
+	if err := h.tags.Delete(c.Request.Context(), currentUserID(c), id); err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrInvalidMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process tag"})
+	}
+}
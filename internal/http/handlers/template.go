@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/services"
+	"github.com/congdv/go-auth/api/internal/templating"
+	"github.com/gin-gonic/gin"
+)
+
+type TemplateHandler struct {
+	templates services.TemplateService
+}
+
+func NewTemplateHandler(templates services.TemplateService) *TemplateHandler {
+	return &TemplateHandler{templates: templates}
+}
+
+func (h *TemplateHandler) List(c *gin.Context) {
+	templates, err := h.templates.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+func (h *TemplateHandler) Get(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	p, placeholders, err := h.templates.Get(c.Request.Context(), currentUserID(c), id)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": p, "placeholders": placeholders})
+}
+
+type validateTemplateRequest struct {
+	Body      string                   `json:"body" binding:"required"`
+	Variables models.TemplateVariables `json:"variables"`
+}
+
+func (h *TemplateHandler) Validate(c *gin.Context) {
+	var req validateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template"})
+		return
+	}
+
+	if err := h.templates.Validate(req.Body, req.Variables); err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+type renderTemplateRequest struct {
+	Values map[string]any `json:"values"`
+}
+
+func (h *TemplateHandler) Render(c *gin.Context) {
+	id, ok := promptIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req renderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid render request"})
+		return
+	}
+
+	out, err := h.templates.Render(c.Request.Context(), currentUserID(c), id, req.Values)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rendered": out})
+}
+
+func writeTemplateError(c *gin.Context, err error) {
+	var tmplErr *templating.Error
+	switch {
+	case errors.As(err, &tmplErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid template", "issues": tmplErr.Issues})
+	case errors.Is(err, services.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process template"})
+	}
+}
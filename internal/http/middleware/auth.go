@@ -1,29 +1,30 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/congdv/go-auth/api/internal/config"
 	"github.com/congdv/go-auth/api/internal/services"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 type ctxKey string
 
 const (
-	ctxUserID ctxKey = "userId"
-	ctxRoles  ctxKey = "roles"
+	ctxUserID    ctxKey = "userId"
+	ctxRoles     ctxKey = "roles"
+	ctxSessionID ctxKey = "sessionId"
+	ctxClaims    ctxKey = "accessClaims"
+	// ctxScopes is only set for personal access tokens.
+	ctxScopes ctxKey = "scopes"
 )
 
-type accessClaims struct {
-	UserId string   `json:"uid"`
-	Roles  []string `json:"roles"`
-	jwt.RegisteredClaims
-}
-
+// Authenticate accepts an access token or, for scripts, a personal access
+// token. Personal tokens are limited to their scopes; see RequireScopes.
 func Authenticate(cfg *config.Config, auth services.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
@@ -32,27 +33,43 @@ func Authenticate(cfg *config.Config, auth services.AuthService) gin.HandlerFunc
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.ParseWithClaims(tokenStr, &accessClaims{}, func(t *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTAccessSecret), nil
-		})
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if strings.HasPrefix(token, services.PersonalTokenPrefix) {
+			claims, err := auth.AuthenticatePersonalToken(ctx.Request.Context(), token)
+			if errors.Is(err, services.ErrInvalidAccessToken) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			} else if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "unable to verify token"})
+				return
+			}
+			ctx.Set(string(ctxClaims), claims)
+			ctx.Set(string(ctxUserID), claims.UserID)
+			ctx.Set(string(ctxRoles), claims.Roles)
+			ctx.Set(string(ctxScopes), claims.Scopes)
+			ctx.Next()
+			return
+		}
 
-		if err != nil || !token.Valid {
+		claims, err := auth.ParseAccessToken(token)
+		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
-		claims, ok := token.Claims.(*accessClaims)
-		if !ok {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
+		if err := auth.CheckRevoked(ctx.Request.Context(), claims); errors.Is(err, services.ErrTokenRevoked) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		} else if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "unable to verify token"})
 			return
 		}
-
-		uid, err := uuid.Parse(claims.UserId)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid uid"})
-		}
-		ctx.Set(string(ctxUserID), uid)
+		ctx.Set(string(ctxClaims), claims)
+		ctx.Set(string(ctxUserID), claims.UserID)
 		ctx.Set(string(ctxRoles), claims.Roles)
+		// Tokens issued before sessions were tracked carry no sid.
+		if claims.SessionID != uuid.Nil {
+			ctx.Set(string(ctxSessionID), claims.SessionID)
+		}
 		ctx.Next()
 	}
 }
@@ -79,3 +96,36 @@ func RequireRoles(roles ...string) gin.HandlerFunc {
 		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
 	}
 }
+
+// RequireScopes limits personal access tokens to routes covered by their
+// scopes; every listed scope is needed. Browser sessions always pass.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, exists := ctx.Get(string(ctxScopes))
+		if !exists {
+			ctx.Next()
+			return
+		}
+		granted, _ := val.([]string)
+		for _, s := range scopes {
+			if !slices.Contains(granted, s) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token is missing scope " + s})
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// RequireSession keeps personal access tokens away from account settings,
+// so a leaked token cannot be used to mint more tokens or lock the owner
+// out.
+func RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, isPersonal := ctx.Get(string(ctxScopes)); isPersonal {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "personal access tokens cannot be used here"})
+			return
+		}
+		ctx.Next()
+	}
+}
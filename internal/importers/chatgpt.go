@@ -0,0 +1,188 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chatGPTConversationsFile = "conversations.json"
+	// maxChatGPTExportBytes bounds conversations.json when read from the
+	// export archive.
+	maxChatGPTExportBytes = 64 << 20
+)
+
+type chatGPTImporter struct{}
+
+// NewChatGPT reads a ChatGPT data export, either conversations.json itself
+// or the archive containing it. Every message the user wrote becomes a
+// prompt titled after its conversation.
+func NewChatGPT() Importer {
+	return chatGPTImporter{}
+}
+
+func (chatGPTImporter) Name() string {
+	return "chatgpt"
+}
+
+type chatGPTConversation struct {
+	ID               string                 `json:"id"`
+	ConversationID   string                 `json:"conversation_id"`
+	Title            string                 `json:"title"`
+	CreateTime       float64                `json:"create_time"`
+	DefaultModelSlug string                 `json:"default_model_slug"`
+	Mapping          map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Message *struct {
+		ID     string `json:"id"`
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		CreateTime *float64 `json:"create_time"`
+		Content    struct {
+			ContentType string            `json:"content_type"`
+			Parts       []json.RawMessage `json:"parts"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+func (imp chatGPTImporter) Parse(name string, data []byte) (Result, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if data, err = conversationsFromArchive(data); err != nil {
+			return Result{}, err
+		}
+		name = path.Join(name, chatGPTConversationsFile)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Result{}, errors.New("expected a JSON array of conversations")
+	}
+
+	var res Result
+	for i, msg := range raw {
+		var conv chatGPTConversation
+		if err := json.Unmarshal(msg, &conv); err != nil {
+			res.Failures = append(res.Failures, Failure{
+				Source:  fmt.Sprintf("%s#%d", name, i),
+				Message: "invalid conversation: " + err.Error(),
+			})
+			continue
+		}
+		res.Records = append(res.Records, imp.records(name, i, conv)...)
+	}
+	return res, nil
+}
+
+func (chatGPTImporter) records(name string, index int, conv chatGPTConversation) []Record {
+	type userMessage struct {
+		id      string
+		text    string
+		created float64
+	}
+	var msgs []userMessage
+	for _, node := range conv.Mapping {
+		m := node.Message
+		if m == nil || m.Author.Role != "user" || m.Content.ContentType != "text" {
+			continue
+		}
+		var parts []string
+		for _, raw := range m.Content.Parts {
+			var s string
+			if json.Unmarshal(raw, &s) == nil && strings.TrimSpace(s) != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		created := conv.CreateTime
+		if m.CreateTime != nil {
+			created = *m.CreateTime
+		}
+		msgs = append(msgs, userMessage{id: m.ID, text: strings.Join(parts, "\n\n"), created: created})
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].created != msgs[j].created {
+			return msgs[i].created < msgs[j].created
+		}
+		return msgs[i].id < msgs[j].id
+	})
+
+	convID := conv.ConversationID
+	if convID == "" {
+		convID = conv.ID
+	}
+	title := strings.TrimSpace(conv.Title)
+
+	records := make([]Record, 0, len(msgs))
+	for n, m := range msgs {
+		rec := Record{
+			Source:    fmt.Sprintf("%s#%d/%s", name, index, m.id),
+			Title:     title,
+			Body:      strings.TrimSpace(m.text),
+			Model:     conv.DefaultModelSlug,
+			Category:  []string{"ChatGPT"},
+			Tags:      []string{"chatgpt"},
+			CreatedAt: unixSeconds(m.created),
+		}
+		if m.id != "" {
+			rec.ExternalID = "chatgpt:" + m.id
+		}
+		if rec.Title == "" {
+			rec.Title = deriveTitle(rec.Body)
+		} else if len(msgs) > 1 {
+			rec.Title = fmt.Sprintf("%s (%d)", title, n+1)
+		}
+		if convID != "" {
+			rec.Description = "Imported from ChatGPT conversation " + convID
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func conversationsFromArchive(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("invalid zip archive")
+	}
+	for _, f := range zr.File {
+		if path.Base(f.Name) != chatGPTConversationsFile {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		out, err := io.ReadAll(io.LimitReader(rc, maxChatGPTExportBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > maxChatGPTExportBytes {
+			return nil, errors.New(chatGPTConversationsFile + " is too large")
+		}
+		return out, nil
+	}
+	return nil, errors.New("archive has no " + chatGPTConversationsFile)
+}
+
+func unixSeconds(s float64) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+	sec := int64(s)
+	return time.Unix(sec, int64((s-float64(sec))*1e9)).UTC()
+}
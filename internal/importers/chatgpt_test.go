@@ -0,0 +1,133 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const chatGPTExport = `[
+  {
+    "id": "c1",
+    "title": "Email help",
+    "create_time": 1700000000,
+    "default_model_slug": "gpt-4o",
+    "mapping": {
+      "root": {"message": null},
+      "m2": {"message": {"id": "m2", "author": {"role": "user"}, "create_time": 1700000100,
+             "content": {"content_type": "text", "parts": ["Make it shorter."]}}},
+      "a1": {"message": {"id": "a1", "author": {"role": "assistant"}, "create_time": 1700000050,
+             "content": {"content_type": "text", "parts": ["Sure."]}}},
+      "m1": {"message": {"id": "m1", "author": {"role": "user"}, "create_time": 1700000010,
+             "content": {"content_type": "text", "parts": ["Write an email", "to my landlord."]}}},
+      "img": {"message": {"id": "img", "author": {"role": "user"},
+             "content": {"content_type": "multimodal_text", "parts": [{"asset": "x"}]}}},
+      "blank": {"message": {"id": "blank", "author": {"role": "user"},
+             "content": {"content_type": "text", "parts": ["  "]}}}
+    }
+  },
+  {
+    "conversation_id": "c2",
+    "title": "",
+    "create_time": 1700001000.5,
+    "mapping": {
+      "m3": {"message": {"id": "m3", "author": {"role": "user"},
+             "content": {"content_type": "text", "parts": ["Summarize this\nlong text"]}}}
+    }
+  },
+  "not a conversation"
+]`
+
+func TestChatGPTMapping(t *testing.T) {
+	res, err := NewChatGPT().Parse("conversations.json", []byte(chatGPTExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{
+			Source: "conversations.json#0/m1", ExternalID: "chatgpt:m1",
+			Title: "Email help (1)", Description: "Imported from ChatGPT conversation c1",
+			Body: "Write an email\n\nto my landlord.", Model: "gpt-4o",
+			Category: []string{"ChatGPT"}, Tags: []string{"chatgpt"},
+			CreatedAt: time.Unix(1700000010, 0).UTC(),
+		},
+		{
+			Source: "conversations.json#0/m2", ExternalID: "chatgpt:m2",
+			Title: "Email help (2)", Description: "Imported from ChatGPT conversation c1",
+			Body: "Make it shorter.", Model: "gpt-4o",
+			Category: []string{"ChatGPT"}, Tags: []string{"chatgpt"},
+			CreatedAt: time.Unix(1700000100, 0).UTC(),
+		},
+		{
+			Source: "conversations.json#1/m3", ExternalID: "chatgpt:m3",
+			Title: "Summarize this", Description: "Imported from ChatGPT conversation c2",
+			Body:     "Summarize this\nlong text",
+			Category: []string{"ChatGPT"}, Tags: []string{"chatgpt"},
+			CreatedAt: time.Unix(1700001000, 5e8).UTC(),
+		},
+	}
+	if !reflect.DeepEqual(res.Records, want) {
+		t.Errorf("records:\ngot  %+v\nwant %+v", res.Records, want)
+	}
+	if len(res.Failures) != 1 || res.Failures[0].Source != "conversations.json#2" {
+		t.Errorf("failures %+v, want one for conversations.json#2", res.Failures)
+	}
+}
+
+func TestChatGPTArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{name: "nested conversations.json", files: map[string]string{
+			"export/chat.html":          "<html></html>",
+			"export/conversations.json": chatGPTExport,
+		}},
+		{name: "no conversations.json", files: map[string]string{"chat.html": ""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for name, body := range tt.files {
+				w, err := zw.Create(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(body))
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := NewChatGPT().Parse("export.zip", buf.Bytes())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Parse succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Records) != 3 {
+				t.Fatalf("got %d records, want 3", len(res.Records))
+			}
+			if got := res.Records[0].Source; got != "export.zip/conversations.json#0/m1" {
+				t.Errorf("source %q", got)
+			}
+		})
+	}
+}
+
+func TestChatGPTRejectsNonArray(t *testing.T) {
+	for _, data := range []string{`{"title": "x"}`, "PK\x03\x04 not really a zip"} {
+		if _, err := NewChatGPT().Parse("in", []byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", data)
+		}
+	}
+}
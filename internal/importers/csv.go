@@ -0,0 +1,187 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxDerivedTitleLength = 80
+
+// columnAliases maps the header names accepted by the generic CSV importer
+// onto record fields. Headers are matched case-insensitively.
+var columnAliases = map[string]string{
+	"title":       "title",
+	"name":        "title",
+	"act":         "title",
+	"body":        "body",
+	"prompt":      "body",
+	"content":     "body",
+	"text":        "body",
+	"description": "description",
+	"notes":       "description",
+	"tags":        "tags",
+	"labels":      "tags",
+	"category":    "category",
+	"folder":      "category",
+	"model":       "model",
+	"id":          "id",
+	"external_id": "id",
+}
+
+type csvImporter struct {
+	name    string
+	columns map[string]string
+	tags    []string
+	idSeed  string
+}
+
+// NewCSV reads spreadsheets exported as CSV. A header row is required and
+// must contain a body column (body, prompt, content or text).
+func NewCSV() Importer {
+	return &csvImporter{name: "csv", columns: columnAliases}
+}
+
+// NewAwesomePrompts reads the awesome-chatgpt-prompts layout: an "act"
+// column used as the title and a "prompt" column. Records are tagged so they
+// are easy to find after import.
+func NewAwesomePrompts() Importer {
+	return &csvImporter{
+		name:    "awesome-chatgpt-prompts",
+		columns: map[string]string{"act": "title", "prompt": "body"},
+		tags:    []string{"awesome-chatgpt-prompts"},
+		idSeed:  "awesome-chatgpt-prompts:",
+	}
+}
+
+func (c *csvImporter) Name() string {
+	return c.name
+}
+
+func (c *csvImporter) Parse(name string, data []byte) (Result, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	// Quoting stays strict: with LazyQuotes a stray quote silently merges
+	// every following row into one field instead of failing that row.
+
+	header, err := r.Read()
+	if err != nil {
+		return Result{}, errors.New("missing header row")
+	}
+	fields := make([]string, len(header))
+	hasBody := false
+	for i, h := range header {
+		fields[i] = c.columns[strings.ToLower(strings.TrimSpace(h))]
+		hasBody = hasBody || fields[i] == "body"
+	}
+	if !hasBody {
+		return Result{}, errors.New("no prompt column in header")
+	}
+
+	var res Result
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				res.Failures = append(res.Failures, Failure{
+					Source:  fmt.Sprintf("%s:%d", name, parseErr.StartLine),
+					Message: parseErr.Err.Error(),
+				})
+				continue
+			}
+			return Result{}, err
+		}
+		line, _ := r.FieldPos(0)
+		source := fmt.Sprintf("%s:%d", name, line)
+
+		rec := Record{Source: source, Tags: append([]string{}, c.tags...)}
+		for i, value := range row {
+			if i >= len(fields) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "title":
+				rec.Title = value
+			case "body":
+				rec.Body = value
+			case "description":
+				rec.Description = value
+			case "model":
+				rec.Model = value
+			case "id":
+				rec.ExternalID = value
+			case "tags":
+				rec.Tags = append(rec.Tags, splitList(value)...)
+			case "category":
+				rec.Category = splitPath(value)
+			}
+		}
+		if isBlank(row) {
+			continue
+		}
+		if rec.Body == "" {
+			res.Failures = append(res.Failures, Failure{Source: source, Message: "missing prompt text"})
+			continue
+		}
+		if rec.Title == "" {
+			rec.Title = deriveTitle(rec.Body)
+		}
+		if rec.ExternalID == "" && c.idSeed != "" {
+			rec.ExternalID = c.idSeed + strings.ToLower(rec.Title)
+		}
+		res.Records = append(res.Records, rec)
+	}
+	return res, nil
+}
+
+func isBlank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits tag cells written with commas, semicolons or pipes.
+func splitList(s string) []string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '|' })
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// splitPath splits a category written as a slash separated path.
+func splitPath(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, "/") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// deriveTitle uses the first line of body, shortened on a rune boundary.
+func deriveTitle(body string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	line = strings.TrimSpace(line)
+	if utf8.RuneCountInString(line) <= maxDerivedTitleLength {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:maxDerivedTitleLength-1])) + "…"
+}
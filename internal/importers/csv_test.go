@@ -0,0 +1,139 @@
+package importers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVHeaderAliases(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		row    string
+		want   Record
+	}{
+		{
+			name:   "canonical",
+			header: "title,body,description,tags,category,model,id",
+			row:    "T,B,D,a;b,Work/Email,gpt-4o,x1",
+			want: Record{
+				Source: "in.csv:2", ExternalID: "x1", Title: "T", Description: "D", Body: "B",
+				Model: "gpt-4o", Category: []string{"Work", "Email"}, Tags: []string{"a", "b"},
+			},
+		},
+		{
+			name:   "aliases in any case",
+			header: "Name,PROMPT,Notes,Labels,Folder,Model,External_ID",
+			row:    "T,B,D,a|b,Work,m,x2",
+			want: Record{
+				Source: "in.csv:2", ExternalID: "x2", Title: "T", Description: "D", Body: "B",
+				Model: "m", Category: []string{"Work"}, Tags: []string{"a", "b"},
+			},
+		},
+		{
+			name:   "content without title derives one",
+			header: " content ,unknown",
+			row:    "\"first line\nsecond\",ignored",
+			want:   Record{Source: "in.csv:2", Title: "first line", Body: "first line\nsecond", Tags: []string{}},
+		},
+		{
+			name:   "text with byte order mark",
+			header: "\xef\xbb\xbftext",
+			row:    "hello",
+			want:   Record{Source: "in.csv:2", Title: "hello", Body: "hello", Tags: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewCSV().Parse("in.csv", []byte(tt.header+"\n"+tt.row+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Failures) != 0 {
+				t.Fatalf("failures: %+v", res.Failures)
+			}
+			if len(res.Records) != 1 {
+				t.Fatalf("got %d records, want 1", len(res.Records))
+			}
+			if got := res.Records[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVRejectsFileWithoutBodyColumn(t *testing.T) {
+	for _, data := range []string{"", "title,notes\nT,N\n"} {
+		if _, err := NewCSV().Parse("in.csv", []byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", data)
+		}
+	}
+}
+
+func TestCSVReportsBadRowsAndKeepsGoing(t *testing.T) {
+	data := strings.Join([]string{
+		"title,body",
+		"one,first",
+		`two,"quoted" then more`,
+		"three,",
+		",",
+		"four,fourth",
+	}, "\n") + "\n"
+
+	res, err := NewCSV().Parse("in.csv", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	for _, r := range res.Records {
+		titles = append(titles, r.Title)
+	}
+	if want := []string{"one", "four"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("records %v, want %v", titles, want)
+	}
+	var sources []string
+	for _, f := range res.Failures {
+		sources = append(sources, f.Source)
+	}
+	if want := []string{"in.csv:3", "in.csv:4"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("failures %+v, want sources %v", res.Failures, want)
+	}
+}
+
+func TestAwesomePromptsIDs(t *testing.T) {
+	data := "act,prompt\n" +
+		"Linux Terminal,I want you to act as a linux terminal.\n" +
+		"\"Travel Guide\",\"I want you to act as a travel guide.\"\n"
+
+	res, err := NewAwesomePrompts().Parse("prompts.csv", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Failures) != 0 {
+		t.Fatalf("failures: %+v", res.Failures)
+	}
+
+	want := []struct{ title, id string }{
+		{"Linux Terminal", "awesome-chatgpt-prompts:linux terminal"},
+		{"Travel Guide", "awesome-chatgpt-prompts:travel guide"},
+	}
+	if len(res.Records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(res.Records), len(want))
+	}
+	for i, w := range want {
+		r := res.Records[i]
+		if r.Title != w.title || r.ExternalID != w.id {
+			t.Errorf("record %d: got title %q id %q, want %q %q", i, r.Title, r.ExternalID, w.title, w.id)
+		}
+		if !reflect.DeepEqual(r.Tags, []string{"awesome-chatgpt-prompts"}) {
+			t.Errorf("record %d: tags %v", i, r.Tags)
+		}
+	}
+
+	// Columns outside the awesome-prompts layout are not picked up.
+	if _, err := NewAwesomePrompts().Parse("p.csv", []byte("title,body\nT,B\n")); err == nil {
+		t.Error("generic headers accepted, want error")
+	}
+}
@@ -0,0 +1,73 @@
+// Package importers reads prompt collections exported by other tools.
+package importers
+
+import (
+	"sort"
+	"time"
+)
+
+// Record is one prompt read from a source, before validation.
+type Record struct {
+	// Source locates the record in its file, e.g. "prompts.csv:12".
+	Source string
+	// ExternalID is stable across re-imports of the same data; it may be
+	// empty when the source has nothing stable to offer.
+	ExternalID  string
+	Title       string
+	Description string
+	Body        string
+	Model       string
+	Category    []string
+	Tags        []string
+	CreatedAt   time.Time
+}
+
+// Failure reports a row or entry that could not be read. The rest of the
+// file is still imported.
+type Failure struct {
+	Source  string
+	Message string
+}
+
+type Result struct {
+	Records  []Record
+	Failures []Failure
+}
+
+// Importer maps one third-party format onto records. Parse returns an error
+// only when the file as a whole cannot be read.
+type Importer interface {
+	Name() string
+	Parse(name string, data []byte) (Result, error)
+}
+
+type Registry struct {
+	importers map[string]Importer
+}
+
+func NewRegistry(importers ...Importer) *Registry {
+	r := &Registry{importers: make(map[string]Importer, len(importers))}
+	for _, imp := range importers {
+		r.importers[imp.Name()] = imp
+	}
+	return r
+}
+
+// Default registers every built-in importer.
+func Default() *Registry {
+	return NewRegistry(NewChatGPT(), NewCSV(), NewAwesomePrompts())
+}
+
+func (r *Registry) Get(name string) (Importer, bool) {
+	imp, ok := r.importers[name]
+	return imp, ok
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.importers))
+	for name := range r.importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,212 @@
+// Package jwtkeys holds the asymmetric keys that sign access tokens.
+//
+// A keyset is a directory of PKCS#8 PEM files named <kid>.pem and a file
+// named ACTIVE holding the key ID that signs new tokens. Every key in the
+// directory verifies, so tokens signed before a rotation stay valid until
+// they expire, and every public key is published as a JWKS.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// ActiveFile names the file holding the active key ID.
+	ActiveFile = "ACTIVE"
+
+	keySuffix  = ".pem"
+	rsaBits    = 2048
+	minRSABits = 2048
+)
+
+// Algorithms lists every algorithm a keyset can sign with.
+var Algorithms = []string{AlgRS256, AlgEdDSA}
+
+var ErrNoKeys = errors.New("jwtkeys: no keys found")
+
+// Key is one signing key. Alg is fixed by the key type, so a token can
+// never choose how it is verified.
+type Key struct {
+	ID      string
+	Alg     string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet is the loaded keys and the one that signs.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	// ids is sorted oldest first.
+	ids []string
+}
+
+// Load reads every key in dir. Without an ACTIVE file the newest key
+// signs.
+func Load(dir string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &KeySet{keys: map[string]*Key{}}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), keySuffix) {
+			continue
+		}
+		kid := strings.TrimSuffix(e.Name(), keySuffix)
+		k, err := readKey(filepath.Join(dir, e.Name()), kid)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[kid] = k
+		s.ids = append(s.ids, kid)
+	}
+	if len(s.ids) == 0 {
+		return nil, ErrNoKeys
+	}
+	sort.Strings(s.ids)
+
+	activeID := s.ids[len(s.ids)-1]
+	if b, err := os.ReadFile(filepath.Join(dir, ActiveFile)); err == nil {
+		activeID = strings.TrimSpace(string(b))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	active, ok := s.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("jwtkeys: active key %q not found in %s", activeID, dir)
+	}
+	s.active = active
+	return s, nil
+}
+
+// Active is the key that signs new tokens.
+func (s *KeySet) Active() *Key {
+	return s.active
+}
+
+// Lookup finds a verification key by ID.
+func (s *KeySet) Lookup(kid string) (*Key, bool) {
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// IDs lists the key IDs, oldest first.
+func (s *KeySet) IDs() []string {
+	return append([]string(nil), s.ids...)
+}
+
+func readKey(path, kid string) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("jwtkeys: %s is not a PKCS#8 PEM private key", path)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: %s: %w", path, err)
+	}
+	return newKey(kid, priv)
+}
+
+func newKey(kid string, priv any) (*Key, error) {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("jwtkeys: key %s: RSA keys must be at least %d bits", kid, minRSABits)
+		}
+		return &Key{ID: kid, Alg: AlgRS256, Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Alg: AlgEdDSA, Private: k, Public: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("jwtkeys: key %s: unsupported key type %T", kid, priv)
+	}
+}
+
+// Generate creates a key for alg. Its ID starts with the creation date so
+// key IDs sort by age.
+func Generate(alg string) (*Key, error) {
+	var priv any
+	var err error
+	switch alg {
+	case AlgRS256:
+		priv, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	case AlgEdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	kid := time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+	return newKey(kid, priv)
+}
+
+// Write saves k to dir as <kid>.pem, readable only by the owner.
+func Write(dir string, k *Key) error {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return writeFile(filepath.Join(dir, k.ID+keySuffix), b)
+}
+
+// Activate makes kid the signing key. Running servers pick it up on their
+// next start.
+func Activate(dir, kid string) error {
+	if _, err := os.Stat(filepath.Join(dir, kid+keySuffix)); err != nil {
+		return fmt.Errorf("jwtkeys: key %q: %w", kid, err)
+	}
+	return writeFile(filepath.Join(dir, ActiveFile), []byte(kid+"\n"))
+}
+
+// Remove deletes a key. The active key cannot be removed.
+func Remove(dir, kid string) error {
+	s, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	if s.active.ID == kid {
+		return fmt.Errorf("jwtkeys: %q is the active key", kid)
+	}
+	return os.Remove(filepath.Join(dir, kid+keySuffix))
+}
+
+// writeFile replaces path atomically so a server starting mid-rotation
+// never reads half a key.
+func writeFile(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type fileSender struct {
+	dir string
+}
+
+// NewFileSender writes each message to dir as an .eml file that any mail
+// client can open. Useful for local development and offline testing.
+func NewFileSender(dir string) (Sender, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mailer: MAIL_DIR is required for the file driver")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileSender{dir: dir}, nil
+}
+
+func (s *fileSender) Send(ctx context.Context, msg Message) error {
+	b, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + uuid.NewString()[:8] + ".eml"
+	// Write then rename so readers never see a partial file.
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
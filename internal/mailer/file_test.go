@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSenderWritesEML(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	s, err := NewFileSender(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := Message{
+		From:    "Keeper <no-reply@example.com>",
+		To:      []string{"user@example.com"},
+		Subject: "Verify your email",
+		Text:    "Open https://example.com/verify?token=t1",
+		HTML:    `<a href="https://example.com/verify?token=t1">Verify</a>`,
+	}
+	for range 2 {
+		if err := s.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d files, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".eml") {
+			t.Errorf("unexpected file %s", e.Name())
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, parts := readMessage(t, raw)
+		if m.Header.Get("Subject") != msg.Subject {
+			t.Errorf("subject %q", m.Header.Get("Subject"))
+		}
+		if parts["text/plain"] != msg.Text || parts["text/html"] != msg.HTML {
+			t.Errorf("parts %q", parts)
+		}
+	}
+}
+
+func TestFileSenderNeedsDir(t *testing.T) {
+	if _, err := NewFileSender(""); err == nil {
+		t.Error("NewFileSender(\"\") succeeded, want error")
+	}
+}
@@ -0,0 +1,20 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+type logSender struct{}
+
+// NewLogSender prints messages to the server log instead of sending them.
+// It is meant for development, where no mail server is configured.
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+func (logSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+	return nil
+}
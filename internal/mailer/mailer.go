@@ -0,0 +1,124 @@
+// Package mailer sends transactional email. Messages are rendered from the
+// embedded templates and handed to a Sender; drivers exist for SMTP, for
+// writing .eml files to a directory and for the server log.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+)
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// Message is a rendered email ready to send.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers a message. Implementations must be safe for concurrent
+// use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender builds the driver selected by MAIL_DRIVER.
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.MailDriver {
+	case "", DriverLog:
+		// The log driver prints reset and verification links, which are
+		// credentials, so it is refused on deployments with secure cookies.
+		if cfg.CookieSecure {
+			return nil, fmt.Errorf("mailer: the log driver writes sign-in links to the server log; set MAIL_DRIVER to smtp or file when COOKIE_SECURE is on")
+		}
+		return NewLogSender(), nil
+	case DriverFile:
+		return NewFileSender(cfg.MailDir)
+	case DriverSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mailer: SMTP_HOST is required for the smtp driver")
+		}
+		return NewSMTPSender(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			TLS:      cfg.SMTPTLS,
+		}), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown driver %q", cfg.MailDriver)
+	}
+}
+
+// Mailer renders templates and sends the result from a fixed address.
+type Mailer struct {
+	sender    Sender
+	from      string
+	templates *templates
+}
+
+func New(sender Sender, from string) (*Mailer, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", from, err)
+	}
+	t, err := parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{sender: sender, from: from, templates: t}, nil
+}
+
+// Send renders the named template with data and sends it to one recipient.
+func (m *Mailer) Send(ctx context.Context, to, name string, data any) error {
+	msg, err := m.templates.render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.From = m.from
+	msg.To = []string{to}
+	return m.sender.Send(ctx, msg)
+}
+
+// Template names.
+const (
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+	TemplateInvitation        = "invitation"
+	TemplateNotification      = "notification"
+)
+
+type PasswordResetData struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+type EmailVerificationData struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+type InvitationData struct {
+	InviterEmail string
+	// Target names what the recipient is being invited to.
+	Target    string
+	URL       string
+	ExpiresAt time.Time
+}
+
+type NotificationData struct {
+	Subject string
+	Message string
+	// URL is optional; when set the email links to it.
+	URL string
+}
@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// readMessage parses rendered mail the way a client would and returns the
+// decoded body of each part by content type, with CRLF line endings
+// turned back into LF.
+func readMessage(t *testing.T, raw []byte) (*mail.Message, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse message: %v\n%s", err, raw)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	if mediaType != "multipart/alternative" {
+		if enc := m.Header.Get("Content-Transfer-Encoding"); enc != "quoted-printable" {
+			t.Fatalf("unexpected encoding %q", enc)
+		}
+		b, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[mediaType] = strings.ReplaceAll(string(b), "\r\n", "\n")
+		return m, parts
+	}
+
+	// multipart.Reader undoes the quoted-printable encoding itself.
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return m, parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		b, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[partType] = strings.ReplaceAll(string(b), "\r\n", "\n")
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	long := strings.Repeat("reset ", 30) + "https://example.com/reset?token=abc=def"
+	tests := []struct {
+		name string
+		msg  Message
+		want map[string]string
+	}{
+		{
+			name: "text only",
+			msg:  Message{Text: "Hello,\n" + long + "\n"},
+			want: map[string]string{"text/plain": "Hello,\n" + long + "\n"},
+		},
+		{
+			name: "text and html",
+			msg:  Message{Text: "Hi ünïcode", HTML: `<p style="x">Hi</p>`},
+			want: map[string]string{"text/plain": "Hi ünïcode", "text/html": `<p style="x">Hi</p>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.From = "Keeper <no-reply@example.com>"
+			tt.msg.To = []string{"a@example.com", "b@example.com"}
+			tt.msg.Subject = "Réinitialiser"
+
+			raw, err := tt.msg.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, parts := readMessage(t, raw)
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+			if err != nil || subject != "Réinitialiser" {
+				t.Errorf("subject %q (%v)", subject, err)
+			}
+			if got := m.Header.Get("To"); got != "a@example.com, b@example.com" {
+				t.Errorf("to %q", got)
+			}
+			if id := m.Header.Get("Message-Id"); !strings.HasSuffix(id, "@example.com>") {
+				t.Errorf("message id %q", id)
+			}
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got parts %q, want %q", parts, tt.want)
+			}
+			for ctype, body := range tt.want {
+				if parts[ctype] != body {
+					t.Errorf("%s body %q, want %q", ctype, parts[ctype], body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("mailer: queue is full")
+	ErrQueueClosed = errors.New("mailer: queue is closed")
+)
+
+type QueueOptions struct {
+	// Size is the number of messages that can wait for a worker.
+	Size    int
+	Workers int
+	// MaxAttempts includes the first try.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles each time up
+	// to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// SendTimeout bounds each delivery attempt.
+	SendTimeout time.Duration
+}
+
+// Queue is a Sender that hands messages to background workers and retries
+// failed deliveries, so callers never wait on the mail server.
+type Queue struct {
+	next Sender
+	opts QueueOptions
+	jobs chan Message
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewQueue(next Sender, opts QueueOptions) *Queue {
+	if opts.Size <= 0 {
+		opts.Size = 100
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 2 * time.Second
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = 5 * time.Minute
+	}
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = 30 * time.Second
+	}
+
+	q := &Queue{
+		next: next,
+		opts: opts,
+		jobs: make(chan Message, opts.Size),
+		quit: make(chan struct{}),
+	}
+	for range opts.Workers {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Send enqueues msg and returns immediately. The context only covers
+// enqueueing; delivery runs on its own timeout.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for queued ones to be delivered.
+// When ctx ends first, pending retries are abandoned.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(q.quit)
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		q.deliver(msg)
+	}
+}
+
+func (q *Queue) deliver(msg Message) {
+	backoff := q.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.opts.SendTimeout)
+		err := q.next.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == q.opts.MaxAttempts {
+			log.Printf("mailer: giving up on %q to %s after %d attempts: %v",
+				msg.Subject, strings.Join(msg.To, ", "), attempt, err)
+			return
+		}
+		log.Printf("mailer: attempt %d for %q failed, retrying in %s: %v", attempt, msg.Subject, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-q.quit:
+			return
+		}
+		backoff = min(backoff*2, q.opts.MaxBackoff)
+	}
+}
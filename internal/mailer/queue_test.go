@@ -0,0 +1,143 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySender fails its first failures calls, then records messages.
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	sent     []Message
+}
+
+func (s *flakySender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("server unavailable")
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *flakySender) counts() (calls, sent int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, len(s.sent)
+}
+
+func fastQueue(next Sender, maxAttempts int) *Queue {
+	return NewQueue(next, QueueOptions{
+		Workers:     1,
+		MaxAttempts: maxAttempts,
+		Backoff:     time.Millisecond,
+		MaxBackoff:  2 * time.Millisecond,
+	})
+}
+
+func TestQueueRetriesUntilDelivered(t *testing.T) {
+	s := &flakySender{failures: 2}
+	q := fastQueue(s, 5)
+	if err := q.Send(context.Background(), Message{Subject: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls, sent := s.counts(); calls != 3 || sent != 1 {
+		t.Errorf("calls %d sent %d, want 3 and 1", calls, sent)
+	}
+}
+
+func TestQueueGivesUpAfterMaxAttempts(t *testing.T) {
+	s := &flakySender{failures: 100}
+	q := fastQueue(s, 3)
+	if err := q.Send(context.Background(), Message{Subject: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls, sent := s.counts(); calls != 3 || sent != 0 {
+		t.Errorf("calls %d sent %d, want 3 and 0", calls, sent)
+	}
+}
+
+func TestQueueCloseDrainsAndRejects(t *testing.T) {
+	s := &flakySender{}
+	q := fastQueue(s, 1)
+	for range 5 {
+		if err := q.Send(context.Background(), Message{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, sent := s.counts(); sent != 5 {
+		t.Errorf("sent %d before Close returned, want 5", sent)
+	}
+	if err := q.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Send after Close = %v, want ErrQueueClosed", err)
+	}
+	// Closing twice is harmless.
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueCloseAbandonsRetriesAtDeadline(t *testing.T) {
+	s := &flakySender{failures: 100}
+	q := NewQueue(s, QueueOptions{Workers: 1, MaxAttempts: 10, Backoff: time.Hour})
+	if err := q.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Close took %s", d)
+	}
+	if calls, _ := s.counts(); calls != 1 {
+		t.Errorf("calls %d, want 1", calls)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 1)
+	q := NewQueue(senderFunc(func(ctx context.Context, msg Message) error {
+		started <- struct{}{}
+		<-block
+		return nil
+	}), QueueOptions{Size: 1, Workers: 1})
+	defer q.Close(context.Background())
+	defer close(block)
+
+	if err := q.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+	<-started // the worker holds the first message
+	if err := q.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Send = %v, want ErrQueueFull", err)
+	}
+}
+
+// senderFunc adapts a function to Sender.
+type senderFunc func(ctx context.Context, msg Message) error
+
+func (f senderFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// dialTimeout applies when the caller's context has no deadline.
+const dialTimeout = 30 * time.Second
+
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// TLS dials with implicit TLS (usually port 465). Otherwise STARTTLS is
+	// used whenever the server offers it.
+	TLS bool
+}
+
+type smtpSender struct {
+	opts SMTPOptions
+}
+
+// NewSMTPSender delivers through an SMTP relay. Authentication is only
+// attempted when a username is set, so local stand-ins such as MailHog or
+// Mailpit work without credentials.
+func NewSMTPSender(opts SMTPOptions) Sender {
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	return &smtpSender{opts: opts}
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	from, err := addresses(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := addresses(msg.To...)
+	if err != nil {
+		return err
+	}
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if !s.opts.TLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.opts.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.opts.Username != "" {
+		auth := smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from[0]); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *smtpSender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+
+	d := &net.Dialer{Deadline: deadline}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.opts.TLS {
+		conn, err = (&tls.Dialer{NetDialer: d, Config: &tls.Config{ServerName: s.opts.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The whole conversation shares the deadline, not just the dial.
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
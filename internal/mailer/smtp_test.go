@@ -0,0 +1,223 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP is a minimal in-process SMTP server standing in for a relay.
+type fakeSMTP struct {
+	host string
+	port int
+	// auth advertises AUTH PLAIN.
+	auth bool
+	// reject is a recipient answered with 550.
+	reject string
+
+	mu          sync.Mutex
+	mails       []fakeMail
+	credentials []string
+}
+
+type fakeMail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func startFakeSMTP(t *testing.T, f *fakeSMTP) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f.host = "127.0.0.1"
+	f.port = ln.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var cur fakeMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if f.auth {
+				tp.PrintfLine("250-fake")
+				tp.PrintfLine("250 AUTH PLAIN")
+			} else {
+				tp.PrintfLine("250 fake")
+			}
+		case "AUTH":
+			_, resp, _ := strings.Cut(arg, " ")
+			cred, _ := base64.StdEncoding.DecodeString(resp)
+			f.mu.Lock()
+			f.credentials = append(f.credentials, string(cred))
+			f.mu.Unlock()
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			cur = fakeMail{from: angleAddr(arg)}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			if addr := angleAddr(arg); addr == f.reject {
+				tp.PrintfLine("550 no such user")
+			} else {
+				cur.to = append(cur.to, addr)
+				tp.PrintfLine("250 ok")
+			}
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			cur.data = data
+			f.mu.Lock()
+			f.mails = append(f.mails, cur)
+			f.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "RSET":
+			cur = fakeMail{}
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (f *fakeSMTP) received() []fakeMail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeMail(nil), f.mails...)
+}
+
+func angleAddr(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+func (f *fakeSMTP) sender(username, password string) Sender {
+	return NewSMTPSender(SMTPOptions{Host: f.host, Port: f.port, Username: username, Password: password})
+}
+
+func TestSMTPSenderDelivers(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "multipart",
+			msg: Message{
+				Text: "Reset your password: https://example.com/reset?token=a=b\n",
+				HTML: `<p><a href="https://example.com/reset?token=a=b">Reset</a></p>`,
+			},
+		},
+		{
+			name: "text only",
+			msg:  Message{Text: "Your code is 123456.\n.\nA line with only a dot.\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startFakeSMTP(t, &fakeSMTP{})
+			tt.msg.From = "Keeper <no-reply@example.com>"
+			tt.msg.To = []string{"User <user@example.com>", "other@example.com"}
+			tt.msg.Subject = "Hello"
+
+			if err := srv.sender("", "").Send(context.Background(), tt.msg); err != nil {
+				t.Fatal(err)
+			}
+			mails := srv.received()
+			if len(mails) != 1 {
+				t.Fatalf("server got %d mails, want 1", len(mails))
+			}
+			got := mails[0]
+			if got.from != "no-reply@example.com" {
+				t.Errorf("MAIL FROM %q", got.from)
+			}
+			if want := []string{"user@example.com", "other@example.com"}; !reflect.DeepEqual(got.to, want) {
+				t.Errorf("RCPT TO %q, want %q", got.to, want)
+			}
+
+			_, parts := readMessage(t, got.data)
+			if parts["text/plain"] != tt.msg.Text {
+				t.Errorf("text part %q, want %q", parts["text/plain"], tt.msg.Text)
+			}
+			if tt.msg.HTML != "" && parts["text/html"] != tt.msg.HTML {
+				t.Errorf("html part %q, want %q", parts["text/html"], tt.msg.HTML)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderAuthenticatesOnlyWithUsername(t *testing.T) {
+	srv := startFakeSMTP(t, &fakeSMTP{auth: true})
+	msg := Message{From: "a@example.com", To: []string{"b@example.com"}, Text: "x"}
+
+	if err := srv.sender("", "").Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.sender("user", "secret").Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if want := []string{"\x00user\x00secret"}; !reflect.DeepEqual(srv.credentials, want) {
+		t.Errorf("credentials %q, want %q", srv.credentials, want)
+	}
+	if len(srv.mails) != 2 {
+		t.Errorf("server got %d mails, want 2", len(srv.mails))
+	}
+}
+
+func TestSMTPSenderReportsRejectedRecipient(t *testing.T) {
+	srv := startFakeSMTP(t, &fakeSMTP{reject: "gone@example.com"})
+	msg := Message{From: "a@example.com", To: []string{"gone@example.com"}, Text: "x"}
+
+	err := srv.sender("", "").Send(context.Background(), msg)
+	var smtpErr *textproto.Error
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+		t.Fatalf("Send = %v, want a 550 reply", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("server got %d mails, want 0", n)
+	}
+}
+
+func TestSMTPSenderRejectsBadAddress(t *testing.T) {
+	s := NewSMTPSender(SMTPOptions{Host: "127.0.0.1", Port: 1})
+	err := s.Send(context.Background(), Message{From: "a@example.com", To: []string{"not an address"}})
+	if err == nil || !strings.Contains(err.Error(), strconv.Quote("not an address")) {
+		t.Errorf("Send = %v, want invalid address", err)
+	}
+}
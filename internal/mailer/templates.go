@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Each email has a <name>.txt with a "subject" block and the plain-text
+// body, and a <name>.html whose "content" block is wrapped in layout.html.
+var templateNames = []string{
+	TemplatePasswordReset,
+	TemplateEmailVerification,
+	TemplateInvitation,
+	TemplateNotification,
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+type templates struct {
+	byName map[string]emailTemplate
+}
+
+func parseTemplates() (*templates, error) {
+	t := &templates{byName: make(map[string]emailTemplate, len(templateNames))}
+	for _, name := range templateNames {
+		text, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("mailer: %s: %w", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("mailer: %s.txt has no subject block", name)
+		}
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("mailer: %s: %w", name, err)
+		}
+		t.byName[name] = emailTemplate{text: text, html: html}
+	}
+	return t, nil
+}
+
+func (t *templates) render(name string, data any) (Message, error) {
+	tmpl, ok := t.byName[name]
+	if !ok {
+		return Message{}, fmt.Errorf("mailer: unknown template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
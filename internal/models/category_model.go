@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Category struct {
+	ID          uuid.UUID     `db:"id" json:"id"`
+	UserID      uuid.UUID     `db:"user_id" json:"user_id"`
+	ParentID    uuid.NullUUID `db:"parent_id" json:"parent_id"`
+	Name        string        `db:"name" json:"name"`
+	Path        string        `db:"path" json:"-"`
+	Position    int           `db:"position" json:"position"`
+	PromptCount int           `db:"prompt_count" json:"prompt_count"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at"`
+
+	Children []*Category `db:"-" json:"children"`
+}
+
+const (
+	CategoryDeleteReparent = "reparent"
+	CategoryDeleteCascade  = "cascade"
+)
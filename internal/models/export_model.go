@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LibraryFormat identifies exported library documents; LibraryVersion is
+// bumped on any incompatible change to their shape.
+const (
+	LibraryFormat  = "keeperprompt.library"
+	LibraryVersion = 1
+)
+
+// LibraryHeader is everything in a library document except its prompts.
+type LibraryHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	ExportLibrary
+}
+
+type LibraryDocument struct {
+	LibraryHeader
+	Prompts []ExportPrompt `json:"prompts"`
+}
+
+// ExportLibrary holds the parts of a library that are small enough to load
+// up front. Prompts are streamed separately.
+type ExportLibrary struct {
+	Categories []ExportCategory `json:"categories"`
+	Tags       []string         `json:"tags"`
+	Favorites  []uuid.UUID      `json:"favorites"`
+}
+
+type ExportCategory struct {
+	ID       uuid.UUID     `db:"id" json:"id"`
+	ParentID uuid.NullUUID `db:"parent_id" json:"parent_id"`
+	Name     string        `db:"name" json:"name"`
+	Position int           `db:"position" json:"position"`
+}
+
+type ExportPrompt struct {
+	ID             uuid.UUID         `db:"id" json:"id"`
+	ExternalID     string            `db:"external_id" json:"external_id,omitempty"`
+	Title          string            `db:"title" json:"title"`
+	Description    string            `db:"description" json:"description"`
+	Body           string            `db:"body" json:"body"`
+	Model          string            `db:"model" json:"model"`
+	Variables      TemplateVariables `db:"variables" json:"variables"`
+	CategoryID     uuid.NullUUID     `db:"category_id" json:"category_id"`
+	Visibility     string            `db:"visibility" json:"visibility"`
+	CurrentVersion int               `db:"current_version" json:"current_version"`
+	Favorited      bool              `db:"favorited" json:"favorited"`
+	Tags           []string          `db:"-" json:"tags"`
+	Versions       ExportVersions    `db:"versions" json:"versions"`
+	CreatedAt      time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time         `db:"updated_at" json:"updated_at"`
+}
+
+type ExportVersion struct {
+	Version     int               `json:"version"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Body        string            `json:"body"`
+	Model       string            `json:"model"`
+	Variables   TemplateVariables `json:"variables"`
+	ChangeNote  string            `json:"change_note"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// ExportVersions is scanned from a JSON aggregate.
+type ExportVersions []ExportVersion
+
+func (v *ExportVersions) Scan(src any) error {
+	switch s := src.(type) {
+	case nil:
+		*v = ExportVersions{}
+		return nil
+	case []byte:
+		return json.Unmarshal(s, v)
+	case string:
+		return json.Unmarshal([]byte(s), v)
+	default:
+		return errors.New("unsupported type for export versions")
+	}
+}
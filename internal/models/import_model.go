@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Conflict strategies applied when an imported prompt's external ID matches
+// an existing prompt.
+const (
+	ImportSkip       = "skip"
+	ImportOverwrite  = "overwrite"
+	ImportRename     = "rename"
+	ImportNewVersion = "new_version"
+)
+
+// ImportSourceNative selects the service's own export formats; any other
+// source names a third-party importer.
+const ImportSourceNative = "keeperprompt"
+
+// Actions reported for each imported prompt.
+const (
+	ImportActionCreated     = "created"
+	ImportActionSkipped     = "skipped"
+	ImportActionOverwritten = "overwritten"
+	ImportActionRenamed     = "renamed"
+	ImportActionVersioned   = "versioned"
+	ImportActionFailed      = "failed"
+)
+
+type ImportFile struct {
+	Name string
+	Data []byte
+}
+
+type ImportOptions struct {
+	Source   string
+	Strategy string
+	DryRun   bool
+}
+
+type ImportReport struct {
+	DryRun   bool               `json:"dry_run"`
+	Strategy string             `json:"strategy"`
+	Summary  map[string]int     `json:"summary"`
+	Items    []ImportItemResult `json:"items"`
+	// Failures lists rows from third-party sources that could not be
+	// read; the rest of the import still applies.
+	Failures []ImportIssue `json:"failures"`
+}
+
+type ImportItemResult struct {
+	Source     string    `json:"source"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Title      string    `json:"title"`
+	Action     string    `json:"action"`
+	PromptID   uuid.UUID `json:"prompt_id"`
+}
+
+type ImportIssue struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// ImportError rejects an import as a whole; nothing is written.
+type ImportError struct {
+	Issues []ImportIssue
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		msgs[i] = fmt.Sprintf("%s: %s", is.Source, is.Message)
+	}
+	return "import failed: " + strings.Join(msgs, "; ")
+}
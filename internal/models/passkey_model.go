@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Passkey is a WebAuthn credential registered to a user.
+type Passkey struct {
+	ID              uuid.UUID      `db:"id" json:"id"`
+	UserID          uuid.UUID      `db:"user_id" json:"-"`
+	CredentialID    []byte         `db:"credential_id" json:"-"`
+	PublicKey       []byte         `db:"public_key" json:"-"`
+	AttestationType string         `db:"attestation_type" json:"-"`
+	Transports      pq.StringArray `db:"transports" json:"transports"`
+	AAGUID          []byte         `db:"aaguid" json:"-"`
+	SignCount       int64          `db:"sign_count" json:"-"`
+	BackupEligible  bool           `db:"backup_eligible" json:"backup_eligible"`
+	BackupState     bool           `db:"backup_state" json:"backup_state"`
+	CloneWarning    bool           `db:"clone_warning" json:"clone_warning"`
+	Name            string         `db:"name" json:"name"`
+	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
+	LastUsedAt      *time.Time     `db:"last_used_at" json:"last_used_at"`
+}
+
+// WebAuthnSession holds the server side of a registration or login
+// ceremony between its begin and finish requests.
+type WebAuthnSession struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    *uuid.UUID `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	Data      []byte     `db:"data"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
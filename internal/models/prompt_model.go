@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Prompt struct {
+	ID             uuid.UUID         `db:"id" json:"id"`
+	UserID         uuid.UUID         `db:"user_id" json:"user_id"`
+	Title          string            `db:"title" json:"title"`
+	Description    string            `db:"description" json:"description"`
+	Body           string            `db:"body" json:"body"`
+	Model          string            `db:"model" json:"model"`
+	Variables      TemplateVariables `db:"variables" json:"variables"`
+	CategoryID     uuid.NullUUID     `db:"category_id" json:"category_id"`
+	Visibility     string            `db:"visibility" json:"visibility"`
+	ExternalID     sql.NullString    `db:"external_id" json:"-"`
+	CurrentVersion int               `db:"current_version" json:"current_version"`
+	FavoriteCount  int               `db:"favorite_count" json:"favorite_count"`
+	CreatedAt      time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time         `db:"updated_at" json:"updated_at"`
+
+	// Favorited reports whether the requesting user favorited the prompt.
+	Favorited bool     `db:"favorited" json:"favorited"`
+	Tags      []string `db:"-" json:"tags"`
+}
+
+type PromptVersion struct {
+	ID          uuid.UUID         `db:"id" json:"id"`
+	PromptID    uuid.UUID         `db:"prompt_id" json:"prompt_id"`
+	Version     int               `db:"version" json:"version"`
+	Title       string            `db:"title" json:"title"`
+	Description string            `db:"description" json:"description"`
+	Body        string            `db:"body" json:"body"`
+	Model       string            `db:"model" json:"model"`
+	Variables   TemplateVariables `db:"variables" json:"variables"`
+	AuthorID    uuid.NullUUID     `db:"author_id" json:"author_id"`
+	ChangeNote  string            `db:"change_note" json:"change_note"`
+	CreatedAt   time.Time         `db:"created_at" json:"created_at"`
+}
+
+type PromptInput struct {
+	Title       string
+	Description string
+	Body        string
+	Model       string
+	Variables   TemplateVariables
+	ChangeNote  string
+
+	// CategoryID files the prompt. On update nil leaves it where it is and
+	// a null ID takes it out of its category.
+	CategoryID *uuid.NullUUID
+
+	// Visibility defaults to private on create and is left unchanged on
+	// update when empty.
+	Visibility string
+
+	// Tags replaces the prompt's tags when non-nil.
+	Tags []string
+}
+
+type PromptListParams struct {
+	Limit  int
+	Offset int
+
+	CategoryID         uuid.NullUUID
+	IncludeDescendants bool
+	FavoritesOnly      bool
+
+	// SavedSearchID narrows the list to a saved search ("smart folder");
+	// the service resolves it into Search.
+	SavedSearchID uuid.NullUUID
+	Search        *SearchFilters
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchFilters is everything that narrows a search. It is plain JSON so it
+// can be stored and replayed.
+type SearchFilters struct {
+	Query         string        `json:"q,omitempty"`
+	CategoryID    uuid.NullUUID `json:"category_id,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	CreatedAfter  *time.Time    `json:"created_after,omitempty"`
+	CreatedBefore *time.Time    `json:"created_before,omitempty"`
+	Model         string        `json:"model,omitempty"`
+	Favorite      bool          `json:"favorite,omitempty"`
+	Visibility    string        `json:"visibility,omitempty"`
+}
+
+func (f SearchFilters) IsZero() bool {
+	return f.Query == "" && !f.CategoryID.Valid && len(f.Tags) == 0 &&
+		f.CreatedAfter == nil && f.CreatedBefore == nil && f.Model == "" && !f.Favorite && f.Visibility == ""
+}
+
+// Value and Scan store filters as JSONB.
+func (f SearchFilters) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+func (f *SearchFilters) Scan(src any) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, f)
+	case string:
+		return json.Unmarshal([]byte(s), f)
+	default:
+		return errors.New("unsupported type for search filters")
+	}
+}
+
+// SearchCursor marks the last row of a page in (rank, updated_at, id) order.
+type SearchCursor struct {
+	Rank      float32   `json:"r"`
+	UpdatedAt time.Time `json:"u"`
+	ID        uuid.UUID `json:"id"`
+}
+
+type SearchParams struct {
+	Filters SearchFilters
+	Limit   int
+	After   *SearchCursor
+}
+
+type SearchResult struct {
+	Prompt         Prompt  `json:"prompt"`
+	Rank           float32 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type SearchHistoryEntry struct {
+	ID        uuid.UUID     `db:"id" json:"id"`
+	UserID    uuid.UUID     `db:"user_id" json:"user_id"`
+	Filters   SearchFilters `db:"filters" json:"filters"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+}
+
+type SavedSearch struct {
+	ID        uuid.UUID     `db:"id" json:"id"`
+	UserID    uuid.UUID     `db:"user_id" json:"user_id"`
+	Name      string        `db:"name" json:"name"`
+	Filters   SearchFilters `db:"filters" json:"filters"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at" json:"updated_at"`
+}
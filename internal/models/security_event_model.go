@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SecurityEventRefreshReuse is a rotated refresh token presented
+	// again. Its whole session is revoked.
+	SecurityEventRefreshReuse = "refresh_token_reuse"
+	// SecurityEventPasskeyCounter is a passkey assertion whose sign count
+	// did not move forward, a sign of a cloned authenticator.
+	SecurityEventPasskeyCounter = "passkey_sign_count_regression"
+)
+
+type SecurityEvent struct {
+	ID        uuid.UUID    `db:"id" json:"id"`
+	UserID    *uuid.UUID   `db:"user_id" json:"-"`
+	Type      string       `db:"type" json:"type"`
+	IP        string       `db:"ip" json:"ip"`
+	UserAgent string       `db:"user_agent" json:"user_agent"`
+	Details   EventDetails `db:"details" json:"details"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+// EventDetails is stored as a JSONB object.
+type EventDetails map[string]any
+
+func (d EventDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *EventDetails) Scan(src any) error {
+	switch s := src.(type) {
+	case nil:
+		*d = EventDetails{}
+		return nil
+	case []byte:
+		return json.Unmarshal(s, d)
+	case string:
+		return json.Unmarshal([]byte(s), d)
+	default:
+		return errors.New("unsupported type for event details")
+	}
+}
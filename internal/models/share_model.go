@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+type ShareLink struct {
+	ID           uuid.UUID      `db:"id" json:"id"`
+	PromptID     uuid.UUID      `db:"prompt_id" json:"prompt_id"`
+	UserID       uuid.UUID      `db:"user_id" json:"user_id"`
+	TokenHash    string         `db:"token_hash" json:"-"`
+	PasswordHash sql.NullString `db:"password_hash" json:"-"`
+	ExpiresAt    *time.Time     `db:"expires_at" json:"expires_at"`
+	ViewCount    int            `db:"view_count" json:"view_count"`
+	LastViewedAt *time.Time     `db:"last_viewed_at" json:"last_viewed_at"`
+	RevokedAt    *time.Time     `db:"revoked_at" json:"revoked_at"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+
+	PasswordAttempts    int        `db:"password_attempts" json:"-"`
+	PasswordWindowStart *time.Time `db:"password_window_start" json:"-"`
+
+	// Token is only set on the link returned at creation; it is never
+	// stored.
+	Token string `db:"-" json:"token,omitempty"`
+}
+
+func (l ShareLink) HasPassword() bool {
+	return l.PasswordHash.Valid
+}
+
+type ShareLinkInput struct {
+	ExpiresAt *time.Time
+	Password  string
+}
+
+// SharedPrompt is the read-only view of a prompt served through a share
+// link.
+type SharedPrompt struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Body        string            `json:"body"`
+	Model       string            `json:"model"`
+	Variables   TemplateVariables `json:"variables"`
+	Tags        []string          `json:"tags"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Tag struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	UserID     uuid.UUID `db:"user_id" json:"user_id"`
+	Name       string    `db:"name" json:"name"`
+	UsageCount int       `db:"usage_count" json:"usage_count"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	VariableString    = "string"
+	VariableNumber    = "number"
+	VariableEnum      = "enum"
+	VariableMultiline = "multiline"
+)
+
+type TemplateVariable struct {
+	Name        string   `json:"name" yaml:"name"`
+	Type        string   `json:"type" yaml:"type"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Optional    bool     `json:"optional,omitempty" yaml:"optional,omitempty"`
+	Default     *string  `json:"default,omitempty" yaml:"default,omitempty"`
+	Options     []string `json:"options,omitempty" yaml:"options,omitempty,flow"`
+}
+
+// TemplateVariables is stored as a JSONB array.
+type TemplateVariables []TemplateVariable
+
+func (v TemplateVariables) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *TemplateVariables) Scan(src any) error {
+	switch s := src.(type) {
+	case nil:
+		*v = TemplateVariables{}
+		return nil
+	case []byte:
+		return json.Unmarshal(s, v)
+	case string:
+		return json.Unmarshal([]byte(s), v)
+	default:
+		return errors.New("unsupported type for template variables")
+	}
+}
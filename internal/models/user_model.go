@@ -11,10 +11,10 @@ type User struct {
 	ID           uuid.UUID      `db:"id" json:"id"`
 	Email        string         `db:"email" json:"email"`
 	PasswordHash sql.NullString `db:"password_hash" json:"-"`
-	Provider     sql.NullString `db:"provider" json:"-"`
-	ProviderID   sql.NullString `db:"provider_id" json:"-"`
-	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time      `db:"updated_at" json:"updated_at"`
+	// EmailVerifiedAt is nil until the user proves they own the address.
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 type Role struct {
@@ -23,12 +23,36 @@ type Role struct {
 }
 
 type RefreshToken struct {
-	ID        uuid.UUID `db:"id"`
-	UserID    uuid.UUID `db:"user_id"`
-	JTI       uuid.UUID `db:"jti"`
-	IsRevoked bool      `db:"is_revoked"`
-	ExpiresAt time.Time `db:"expires_at"`
-	CreatedAt time.Time `db:"created_at"`
+	ID         uuid.UUID  `db:"id"`
+	UserID     uuid.UUID  `db:"user_id"`
+	JTI        uuid.UUID  `db:"jti"`
+	SessionID  uuid.UUID  `db:"session_id"`
+	ParentJTI  *uuid.UUID `db:"parent_jti"`
+	ReplacedBy *uuid.UUID `db:"replaced_by"`
+	IsRevoked  bool       `db:"is_revoked"`
+	UserAgent  string     `db:"user_agent"`
+	IP         string     `db:"ip"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+}
+
+// ClientInfo describes the device a request came from.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one signed-in device: the live refresh token of a login and
+// its rotations.
+type Session struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	UserAgent  string    `db:"user_agent" json:"user_agent"`
+	IP         string    `db:"ip" json:"ip"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	LastUsedAt time.Time `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+	Current    bool      `db:"-" json:"current"`
 }
 
 type AuthUser struct {
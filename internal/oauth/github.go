@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const githubAPI = "https://api.github.com"
+
+type githubProvider struct {
+	oauth oauth2.Config
+}
+
+// NewGitHub logs in with a GitHub OAuth app. GitHub is plain OAuth 2.0, so
+// the identity comes from the REST API rather than an ID token.
+func NewGitHub(clientID, clientSecret, redirectURL string) Provider {
+	return &githubProvider{
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+	}
+}
+
+func (p *githubProvider) Name() string { return "github" }
+
+// GitHub issues no ID token, so the nonce is not sent.
+func (p *githubProvider) AuthCodeURL(ctx context.Context, flow Flow) (string, error) {
+	return p.oauth.AuthCodeURL(flow.State, oauth2.S256ChallengeOption(flow.Verifier)), nil
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code string, flow Flow) (Identity, error) {
+	ctx = withHTTPClient(ctx)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, err
+	}
+	src := p.oauth.TokenSource(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, src, githubAPI+"/user", &user); err != nil {
+		return Identity{}, err
+	}
+
+	// The profile email is optional and may be unverified; only the primary
+	// verified address from the emails API identifies the account.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, src, githubAPI+"/user/emails", &emails); err != nil {
+		return Identity{}, err
+	}
+	id := Identity{
+		Provider: "github",
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Picture:  user.AvatarURL,
+	}
+	if id.Name == "" {
+		id.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			id.Email, id.EmailVerified = e.Email, true
+			break
+		}
+	}
+	if id.Email == "" {
+		return Identity{}, ErrNoVerifiedEmail
+	}
+	return id, nil
+}
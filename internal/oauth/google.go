@@ -0,0 +1,30 @@
+package oauth
+
+import (
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// NewGoogle is an OpenID Connect provider with Google's published metadata,
+// so no discovery round trip is needed.
+func NewGoogle(clientID, clientSecret, redirectURL string) Provider {
+	return &oidcProvider{
+		name: "google",
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+			Endpoint:     google.Endpoint,
+		},
+		authParams:    []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("prompt", "select_account")},
+		issuerAliases: []string{"accounts.google.com"},
+		meta: &oidcMetadata{
+			Issuer:                "https://accounts.google.com",
+			AuthorizationEndpoint: google.Endpoint.AuthURL,
+			TokenEndpoint:         google.Endpoint.TokenURL,
+			UserinfoEndpoint:      "https://openidconnect.googleapis.com/v1/userinfo",
+			JWKSURI:               "https://www.googleapis.com/oauth2/v3/certs",
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// oidcMetadata is the subset of a discovery document the login flow needs.
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider logs in against an OpenID Connect issuer. Metadata is either
+// static (Google) or read once from the issuer's discovery document. The
+// identity comes from the ID token, verified against the issuer's JWKS.
+type oidcProvider struct {
+	name         string
+	discoveryURL string
+	oauth        oauth2.Config
+	authParams   []oauth2.AuthCodeOption
+	// issuerAliases are accepted in the iss claim besides meta.Issuer.
+	issuerAliases []string
+
+	mu   sync.Mutex
+	meta *oidcMetadata
+	keys *keySet
+}
+
+// NewOIDC configures a generic OpenID Connect provider from its discovery
+// URL, usually <issuer>/.well-known/openid-configuration. Discovery runs on
+// first use so an unreachable issuer does not stop the server starting.
+func NewOIDC(name, discoveryURL, clientID, clientSecret, redirectURL string) Provider {
+	if name == "" {
+		name = "oidc"
+	}
+	return &oidcProvider{
+		name:         name,
+		discoveryURL: discoveryURL,
+		oauth: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+	}
+}
+
+func (p *oidcProvider) Name() string { return p.name }
+
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcMetadata, *keySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta == nil {
+		var m oidcMetadata
+		if err := getJSON(ctx, nil, p.discoveryURL, &m); err != nil {
+			return nil, nil, err
+		}
+		if m.Issuer == "" || m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+			return nil, nil, errors.New("oauth: discovery document is missing required fields")
+		}
+		// The discovery document must belong to the issuer it names.
+		if base, ok := strings.CutSuffix(p.discoveryURL, discoveryPath); ok && strings.TrimSuffix(m.Issuer, "/") != strings.TrimSuffix(base, "/") {
+			return nil, nil, fmt.Errorf("oauth: discovery issuer %q does not match %q", m.Issuer, base)
+		}
+		p.meta = &m
+		p.oauth.Endpoint = oauth2.Endpoint{AuthURL: m.AuthorizationEndpoint, TokenURL: m.TokenEndpoint}
+	}
+	if p.keys == nil {
+		p.keys = newKeySet(p.meta.JWKSURI)
+	}
+	return p.meta, p.keys, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, flow Flow) (string, error) {
+	if _, _, err := p.metadata(ctx); err != nil {
+		return "", err
+	}
+	opts := append([]oauth2.AuthCodeOption{
+		oauth2.S256ChallengeOption(flow.Verifier),
+		oauth2.SetAuthURLParam("nonce", flow.Nonce),
+	}, p.authParams...)
+	return p.oauth.AuthCodeURL(flow.State, opts...), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, flow Flow) (Identity, error) {
+	meta, keys, err := p.metadata(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	ctx = withHTTPClient(ctx)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, err
+	}
+
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return Identity{}, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	issuers := append([]string{meta.Issuer}, p.issuerAliases...)
+	claims, err := verifyIDToken(ctx, raw, keys, issuers, p.oauth.ClientID, flow.Nonce)
+	if err != nil {
+		return Identity{}, err
+	}
+	id := Identity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}
+
+	// Some issuers keep profile claims out of the ID token. The userinfo
+	// response is only trusted for the subject the token vouched for.
+	if id.Email == "" && meta.UserinfoEndpoint != "" {
+		var info struct {
+			Sub           string   `json:"sub"`
+			Email         string   `json:"email"`
+			EmailVerified flexBool `json:"email_verified"`
+			Name          string   `json:"name"`
+			Picture       string   `json:"picture"`
+		}
+		if err := getJSON(ctx, p.oauth.TokenSource(ctx, token), meta.UserinfoEndpoint, &info); err != nil {
+			return Identity{}, err
+		}
+		if info.Sub != claims.Subject {
+			return Identity{}, fmt.Errorf("%w: userinfo subject mismatch", ErrInvalidIDToken)
+		}
+		id.Email, id.EmailVerified = info.Email, bool(info.EmailVerified)
+		if id.Name == "" {
+			id.Name, id.Picture = info.Name, info.Picture
+		}
+	}
+	if id.Email == "" {
+		return Identity{}, ErrNoVerifiedEmail
+	}
+	return id, nil
+}
+
+// flexBool accepts both true and "true"; some issuers send claims as
+// strings.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = flexBool(v)
+	case string:
+		*b = flexBool(strings.EqualFold(v, "true"))
+	default:
+		*b = false
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "client-1"
+	testClientSecret = "client-secret"
+)
+
+// fakeIssuer is a local OpenID Connect provider: discovery, JWKS and a
+// token endpoint that checks PKCE and returns an ID token.
+type fakeIssuer struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*ecdsa.PrivateKey
+	signKid string
+	// requests maps issued codes to the authorization request.
+	requests    map[string]url.Values
+	jwksFetches int
+	// jwksGate, when set, holds JWKS responses until it is closed.
+	jwksGate chan struct{}
+	// token builds the ID token; it defaults to a valid ES256 token.
+	token func(claims jwt.MapClaims) string
+	// claims adjusts the default claims before signing.
+	claims func(jwt.MapClaims)
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	f := &fakeIssuer{keys: map[string]*ecdsa.PrivateKey{}, requests: map[string]url.Values{}}
+	f.addKey(t, "k1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcMetadata{
+			Issuer:                f.srv.URL,
+			AuthorizationEndpoint: f.srv.URL + "/authorize",
+			TokenEndpoint:         f.srv.URL + "/token",
+			JWKSURI:               f.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", f.serveJWKS)
+	mux.HandleFunc("POST /token", f.serveToken)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeIssuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[kid] = k
+	f.signKid = kid
+}
+
+func (f *fakeIssuer) provider() *oidcProvider {
+	return NewOIDC("test", f.srv.URL+discoveryPath, testClientID, testClientSecret, "http://app.test/callback").(*oidcProvider)
+}
+
+func (f *fakeIssuer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.jwksFetches++
+	gate := f.jwksGate
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, k := range f.keys {
+		doc.Keys = append(doc.Keys, jwk{
+			Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, 32))),
+			Y: base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	f.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	json.NewEncoder(w).Encode(doc)
+}
+
+func (f *fakeIssuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	req, ok := f.requests[r.PostForm.Get("code")]
+	delete(f.requests, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.Get("code_challenge") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            f.srv.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "user@example.com",
+		"email_verified": "true",
+		"name":           "Test User",
+		"nonce":          req.Get("nonce"),
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+	if f.claims != nil {
+		f.claims(claims)
+	}
+	sign := f.token
+	if sign == nil {
+		sign = f.signES256
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     sign(claims),
+	})
+}
+
+func (f *fakeIssuer) signES256(claims jwt.MapClaims) string {
+	f.mu.Lock()
+	kid, key := f.signKid, f.keys[f.signKid]
+	f.mu.Unlock()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	t.Header["kid"] = kid
+	s, err := t.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// login runs the browser leg: it builds the authorization URL, has the
+// issuer approve it and returns the code.
+func (f *fakeIssuer) login(t *testing.T, p Provider, flow Flow) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("state") != flow.State || q.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	code := randomString(8)
+	f.mu.Lock()
+	f.requests[code] = q
+	f.mu.Unlock()
+	return code
+}
+
+func TestOIDCLogin(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider()
+	flow := NewFlow()
+
+	id, err := p.Exchange(context.Background(), f.login(t, p, flow), flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Provider: "test", Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"}
+	if id != want {
+		t.Errorf("identity %+v, want %+v", id, want)
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(jwt.MapClaims)
+		token  func(f *fakeIssuer, claims jwt.MapClaims) string
+	}{
+		{name: "wrong issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "several audiences without azp", claims: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+		}},
+		{name: "azp names another client", claims: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+			c["azp"] = "other"
+		}},
+		{name: "expired", claims: func(c jwt.MapClaims) {
+			c["iat"] = time.Now().Add(-time.Hour).Unix()
+			c["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}},
+		{name: "no expiry", claims: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "nonce mismatch", claims: func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+		{name: "no subject", claims: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "HS256 signed with the client secret", token: func(f *fakeIssuer, c jwt.MapClaims) string {
+			t := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+			t.Header["kid"] = "k1"
+			s, _ := t.SignedString([]byte(testClientSecret))
+			return s
+		}},
+		{name: "alg none", token: func(f *fakeIssuer, c jwt.MapClaims) string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return s
+		}},
+		{name: "signed by an unpublished key", token: func(f *fakeIssuer, c jwt.MapClaims) string {
+			k, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			t := jwt.NewWithClaims(jwt.SigningMethodES256, c)
+			t.Header["kid"] = "k1"
+			s, _ := t.SignedString(k)
+			return s
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			f.claims = tt.claims
+			if tt.token != nil {
+				f.token = func(c jwt.MapClaims) string { return tt.token(f, c) }
+			}
+			p := f.provider()
+			flow := NewFlow()
+
+			_, err := p.Exchange(context.Background(), f.login(t, p, flow), flow)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Exchange = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCAcceptsAuthorizedParty(t *testing.T) {
+	f := newFakeIssuer(t)
+	f.claims = func(c jwt.MapClaims) {
+		c["aud"] = []string{testClientID, "other"}
+		c["azp"] = testClientID
+	}
+	p := f.provider()
+	flow := NewFlow()
+	if _, err := p.Exchange(context.Background(), f.login(t, p, flow), flow); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCRejectsWrongPKCEVerifier(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider()
+	flow := NewFlow()
+	code := f.login(t, p, flow)
+
+	flow.Verifier = NewFlow().Verifier
+	_, err := p.Exchange(context.Background(), code, flow)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Exchange = %v, want invalid_grant", err)
+	}
+}
+
+func TestOIDCRefetchesKeysForUnknownKid(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider()
+	exchange := func() error {
+		flow := NewFlow()
+		_, err := p.Exchange(context.Background(), f.login(t, p, flow), flow)
+		return err
+	}
+
+	if err := exchange(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rotation right after a fetch is not picked up until the refresh
+	// interval has passed, so unknown kids cannot force a fetch each.
+	f.addKey(t, "k2")
+	if err := exchange(); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("Exchange = %v, want ErrInvalidIDToken", err)
+	}
+	f.mu.Lock()
+	fetches := f.jwksFetches
+	f.mu.Unlock()
+	if fetches != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", fetches)
+	}
+
+	p.keys.mu.Lock()
+	p.keys.fetched = time.Now().Add(-2 * jwksRefreshInterval)
+	p.keys.mu.Unlock()
+	if err := exchange(); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	fetches = f.jwksFetches
+	f.mu.Unlock()
+	if fetches != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", fetches)
+	}
+}
+
+func TestKeySetFetchDoesNotBlockKnownKeys(t *testing.T) {
+	f := newFakeIssuer(t)
+	ks := newKeySet(f.srv.URL + "/jwks")
+	ctx := context.Background()
+	if _, err := ks.key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := make(chan struct{})
+	f.mu.Lock()
+	f.jwksGate = gate
+	f.mu.Unlock()
+	ks.mu.Lock()
+	ks.fetched = time.Time{}
+	ks.mu.Unlock()
+
+	// Two lookups of an unknown kid share one slow fetch.
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := ks.key(ctx, "missing")
+			errs <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ks.mu.Lock()
+		inFlight := ks.fetching != nil
+		ks.mu.Unlock()
+		if inFlight {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fetch never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ks.key(ctx, "k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("known key blocked behind a JWKS fetch")
+	}
+
+	close(gate)
+	for range 2 {
+		if err := <-errs; err == nil {
+			t.Error("unknown kid resolved")
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.jwksFetches != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", f.jwksFetches)
+	}
+}
@@ -0,0 +1,137 @@
+// Package oauth implements social login providers. Each provider turns an
+// authorization code into a verified Identity; the HTTP flow around it
+// (state, cookies, token issuing) lives in the handlers.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"golang.org/x/oauth2"
+)
+
+var ErrNoVerifiedEmail = errors.New("provider did not return a verified email address")
+
+// httpClient is used for every provider call, including the token exchange.
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// Identity is the user as described by the provider. EmailVerified is
+// false when the provider has not checked Email; such an address must not
+// create or claim an account.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Flow holds the per-login secrets that must survive the round trip to the
+// provider: the CSRF state, the PKCE code verifier and the OIDC nonce.
+type Flow struct {
+	State    string `json:"s"`
+	Verifier string `json:"v"`
+	Nonce    string `json:"n"`
+}
+
+func NewFlow() Flow {
+	return Flow{
+		State:    randomString(32),
+		Verifier: oauth2.GenerateVerifier(),
+		Nonce:    randomString(32),
+	}
+}
+
+type Provider interface {
+	Name() string
+	// AuthCodeURL sends the state, the S256 PKCE challenge and, for OpenID
+	// Connect providers, the nonce.
+	AuthCodeURL(ctx context.Context, flow Flow) (string, error)
+	// Exchange redeems the callback code with the PKCE verifier and returns
+	// the user's identity.
+	Exchange(ctx context.Context, code string, flow Flow) (Identity, error)
+}
+
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry registers every provider that has client credentials in cfg.
+func NewRegistry(cfg *config.Config) *Registry {
+	r := &Registry{providers: map[string]Provider{}}
+	if cfg.GoogleClientID != "" && cfg.GoogleClientSecret != "" {
+		r.Register(NewGoogle(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectUrl))
+	}
+	if cfg.GitHubClientID != "" && cfg.GitHubClientSecret != "" {
+		r.Register(NewGitHub(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubRedirectURL))
+	}
+	if cfg.OIDCDiscoveryURL != "" && cfg.OIDCClientID != "" {
+		r.Register(NewOIDC(cfg.OIDCName, cfg.OIDCDiscoveryURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL))
+	}
+	return r
+}
+
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func withHTTPClient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
+
+// getJSON fetches url with the access token and decodes the response.
+func getJSON(ctx context.Context, src oauth2.TokenSource, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if src != nil {
+		t, err := src.Token()
+		if err != nil {
+			return err
+		}
+		t.SetAuthHeader(req)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		return fmt.Errorf("oauth: GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
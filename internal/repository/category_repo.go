@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrCategoryCycle = errors.New("category cannot be moved into its own subtree")
+
+type CategoryRepo interface {
+	Create(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, name string) (models.Category, error)
+	FindByID(ctx context.Context, userID, id uuid.UUID) (models.Category, error)
+	List(ctx context.Context, userID uuid.UUID) ([]models.Category, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Category, error)
+	Move(ctx context.Context, userID, id uuid.UUID, parentID uuid.NullUUID, position int) (models.Category, error)
+	Reorder(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, ids []uuid.UUID) error
+	Delete(ctx context.Context, userID, id uuid.UUID, strategy string) error
+}
+
+type categoryRepo struct {
+	db *sqlx.DB
+}
+
+func NewCategoryRepo(db *sqlx.DB) CategoryRepo {
+	return &categoryRepo{db: db}
+}
+
+func (r *categoryRepo) Create(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, name string) (models.Category, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Category{}, err
+	}
+	defer tx.Rollback()
+
+	parentPath := "/"
+	if parentID.Valid {
+		parent, err := lockCategory(ctx, tx, userID, parentID.UUID)
+		if err != nil {
+			return models.Category{}, err
+		}
+		parentPath = parent.Path
+	}
+
+	now := time.Now()
+	c := models.Category{
+		ID:        uuid.New(),
+		UserID:    userID,
+		ParentID:  parentID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	c.Path = parentPath + c.ID.String() + "/"
+	if c.Position, err = nextPosition(ctx, tx, userID, parentID); err != nil {
+		return models.Category{}, err
+	}
+
+	if _, err := tx.NamedExecContext(ctx, `
+		INSERT INTO categories (id, user_id, parent_id, name, path, position, created_at, updated_at)
+		VALUES (:id, :user_id, :parent_id, :name, :path, :position, :created_at, :updated_at)
+	`, &c); err != nil {
+		return models.Category{}, err
+	}
+
+	return c, tx.Commit()
+}
+
+func (r *categoryRepo) FindByID(ctx context.Context, userID, id uuid.UUID) (models.Category, error) {
+	var c models.Category
+	err := r.db.GetContext(ctx, &c, `SELECT * FROM categories WHERE id = $1 AND user_id = $2`, id, userID)
+	return c, err
+}
+
+func (r *categoryRepo) List(ctx context.Context, userID uuid.UUID) ([]models.Category, error) {
+	categories := []models.Category{}
+	err := r.db.SelectContext(ctx, &categories, `
+		SELECT c.*, (SELECT COUNT(*) FROM prompts p WHERE p.category_id = c.id) AS prompt_count
+		FROM categories c
+		WHERE c.user_id = $1
+		ORDER BY c.position, c.name
+	`, userID)
+	return categories, err
+}
+
+func (r *categoryRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Category, error) {
+	var c models.Category
+	err := r.db.GetContext(ctx, &c, `
+		UPDATE categories SET name = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`, id, userID, name)
+	return c, err
+}
+
+// Move re-parents a category and its whole subtree, rewriting descendant
+// paths, then places it at position among its new siblings (negative
+// positions append).
+func (r *categoryRepo) Move(ctx context.Context, userID, id uuid.UUID, parentID uuid.NullUUID, position int) (models.Category, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Category{}, err
+	}
+	defer tx.Rollback()
+
+	c, err := lockCategory(ctx, tx, userID, id)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	newPath := "/" + c.ID.String() + "/"
+	if parentID.Valid {
+		parent, err := lockCategory(ctx, tx, userID, parentID.UUID)
+		if err != nil {
+			return models.Category{}, err
+		}
+		if strings.HasPrefix(parent.Path, c.Path) {
+			return models.Category{}, ErrCategoryCycle
+		}
+		newPath = parent.Path + c.ID.String() + "/"
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE categories SET position = position - 1
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position > $3 AND id <> $4
+	`, userID, c.ParentID, c.Position, id); err != nil {
+		return models.Category{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE categories SET path = $3::text || substr(path, length($2::text) + 1)
+		WHERE user_id = $1 AND path LIKE $2::text || '%'
+	`, userID, c.Path, newPath); err != nil {
+		return models.Category{}, err
+	}
+
+	var siblings int
+	if err := tx.GetContext(ctx, &siblings, `
+		SELECT COUNT(*) FROM categories
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND id <> $3
+	`, userID, parentID, id); err != nil {
+		return models.Category{}, err
+	}
+	if position < 0 || position > siblings {
+		position = siblings
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE categories SET position = position + 1
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position >= $3 AND id <> $4
+	`, userID, parentID, position, id); err != nil {
+		return models.Category{}, err
+	}
+
+	var moved models.Category
+	if err := tx.GetContext(ctx, &moved, `
+		UPDATE categories SET parent_id = $3, position = $4, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`, id, userID, parentID, position); err != nil {
+		return models.Category{}, err
+	}
+
+	return moved, tx.Commit()
+}
+
+// Reorder assigns positions to all children of parentID in the given order.
+// ids must list every sibling exactly once.
+func (r *categoryRepo) Reorder(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, ids []uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var siblings []uuid.UUID
+	if err := tx.SelectContext(ctx, &siblings, `
+		SELECT id FROM categories
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2
+		FOR UPDATE
+	`, userID, parentID); err != nil {
+		return err
+	}
+
+	wanted := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	if len(wanted) != len(ids) || len(siblings) != len(ids) {
+		return sql.ErrNoRows
+	}
+	for _, id := range siblings {
+		if !wanted[id] {
+			return sql.ErrNoRows
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE categories c SET position = o.ord - 1, updated_at = NOW()
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+		WHERE c.id = o.id AND c.user_id = $1
+	`, userID, pq.Array(uuidStrings(ids))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a category. With the reparent strategy its children and
+// prompts move up to its parent; with cascade the whole subtree and every
+// prompt filed in it are deleted.
+func (r *categoryRepo) Delete(ctx context.Context, userID, id uuid.UUID, strategy string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := lockCategory(ctx, tx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	switch strategy {
+	case models.CategoryDeleteCascade:
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM prompts
+			WHERE user_id = $1 AND category_id IN (
+				SELECT id FROM categories WHERE user_id = $1 AND path LIKE $2::text || '%'
+			)
+		`, userID, c.Path); err != nil {
+			return err
+		}
+	default:
+		parentPath := strings.TrimSuffix(c.Path, c.ID.String()+"/")
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE prompts SET category_id = $3 WHERE user_id = $1 AND category_id = $2
+		`, userID, id, c.ParentID); err != nil {
+			return err
+		}
+		next, err := nextPosition(ctx, tx, userID, c.ParentID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE categories SET parent_id = $3, position = position + $4, updated_at = NOW()
+			WHERE user_id = $1 AND parent_id = $2
+		`, userID, id, c.ParentID, next); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE categories SET path = $3::text || substr(path, length($2::text) + 1)
+			WHERE user_id = $1 AND path LIKE $2::text || '%' AND id <> $4
+		`, userID, c.Path, parentPath, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM categories WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE categories SET position = position - 1
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND position > $3
+	`, userID, c.ParentID, c.Position); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func lockCategory(ctx context.Context, tx *sqlx.Tx, userID, id uuid.UUID) (models.Category, error) {
+	var c models.Category
+	err := tx.GetContext(ctx, &c, `SELECT * FROM categories WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID)
+	return c, err
+}
+
+func nextPosition(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, parentID uuid.NullUUID) (int, error) {
+	var next int
+	err := tx.GetContext(ctx, &next, `
+		SELECT COALESCE(MAX(position) + 1, 0) FROM categories
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2
+	`, userID, parentID)
+	return next, err
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
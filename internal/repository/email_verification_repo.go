@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type EmailVerificationRepo interface {
+	// Create stores a new token and retires any the user still had.
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// LastSentAt is the zero time when the user was never sent a token.
+	LastSentAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	// Redeem consumes a live token and marks its user verified, unlinking
+	// any provider attached while the address was unproven. It returns
+	// sql.ErrNoRows for unknown, used or expired tokens.
+	Redeem(ctx context.Context, tokenHash string) (uuid.UUID, error)
+}
+
+type emailVerificationRepo struct {
+	db *sqlx.DB
+}
+
+func NewEmailVerificationRepo(db *sqlx.DB) EmailVerificationRepo {
+	return &emailVerificationRepo{db: db}
+}
+
+func (r *emailVerificationRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO email_verification_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, uuid.New(), userID, tokenHash, expiresAt, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *emailVerificationRepo) LastSentAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var last sql.NullTime
+	err := r.db.GetContext(ctx, &last, `
+		SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1
+	`, userID)
+	return last.Time, err
+}
+
+func (r *emailVerificationRepo) Redeem(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	if err := tx.GetContext(ctx, &userID, `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash); err != nil {
+		return uuid.Nil, err
+	}
+	if err := dropUnverifiedIdentities(ctx, tx, userID); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`, userID); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, tx.Commit()
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ExportRepo interface {
+	// Snapshot runs fn against a consistent, read-only view of the user's
+	// library.
+	Snapshot(ctx context.Context, userID uuid.UUID, fn func(ExportSnapshot) error) error
+}
+
+type ExportSnapshot interface {
+	Library(ctx context.Context) (models.ExportLibrary, error)
+	// EachPrompt streams prompts, with their versions, one row at a time.
+	EachPrompt(ctx context.Context, fn func(models.ExportPrompt) error) error
+}
+
+type exportRepo struct {
+	db *sqlx.DB
+}
+
+func NewExportRepo(db *sqlx.DB) ExportRepo {
+	return &exportRepo{db: db}
+}
+
+func (r *exportRepo) Snapshot(ctx context.Context, userID uuid.UUID, fn func(ExportSnapshot) error) error {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&exportSnapshot{tx: tx, userID: userID}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type exportSnapshot struct {
+	tx     *sqlx.Tx
+	userID uuid.UUID
+}
+
+func (s *exportSnapshot) Library(ctx context.Context) (models.ExportLibrary, error) {
+	lib := models.ExportLibrary{
+		Categories: []models.ExportCategory{},
+		Tags:       []string{},
+		Favorites:  []uuid.UUID{},
+	}
+
+	// Shallower paths first, so parents always precede their children.
+	if err := s.tx.SelectContext(ctx, &lib.Categories, `
+		SELECT c.id, c.parent_id, c.name, c.position FROM categories c
+		WHERE c.user_id = $1
+		ORDER BY length(c.path), c.position, c.name
+	`, s.userID); err != nil {
+		return models.ExportLibrary{}, err
+	}
+	if err := s.tx.SelectContext(ctx, &lib.Tags, `
+		SELECT t.name FROM tags t WHERE t.user_id = $1 ORDER BY t.name
+	`, s.userID); err != nil {
+		return models.ExportLibrary{}, err
+	}
+	if err := s.tx.SelectContext(ctx, &lib.Favorites, `
+		SELECT f.prompt_id FROM favorites f WHERE f.user_id = $1 ORDER BY f.created_at
+	`, s.userID); err != nil {
+		return models.ExportLibrary{}, err
+	}
+	return lib, nil
+}
+
+type exportPromptRow struct {
+	models.ExportPrompt
+	TagNames pq.StringArray `db:"tag_names"`
+}
+
+func (s *exportSnapshot) EachPrompt(ctx context.Context, fn func(models.ExportPrompt) error) error {
+	rows, err := s.tx.QueryxContext(ctx, `
+		SELECT p.id, COALESCE(p.external_id, '') AS external_id, p.title, p.description, p.body, p.model, p.variables, p.category_id,
+			p.visibility, p.current_version, p.created_at, p.updated_at,
+			`+favoritedBy("$1")+` AS favorited,
+			ARRAY(
+				SELECT t.name::text FROM prompt_tags pt JOIN tags t ON t.id = pt.tag_id
+				WHERE pt.prompt_id = p.id ORDER BY t.name
+			) AS tag_names,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'version', v.version, 'title', v.title, 'description', v.description,
+					'body', v.body, 'model', v.model, 'variables', v.variables,
+					'change_note', v.change_note, 'created_at', v.created_at
+				) ORDER BY v.version)
+				FROM prompt_versions v WHERE v.prompt_id = p.id
+			), '[]'::json) AS versions
+		FROM prompts p
+		WHERE p.user_id = $1
+		ORDER BY p.created_at, p.id
+	`, s.userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row exportPromptRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		p := row.ExportPrompt
+		p.Tags = []string(row.TagNames)
+		if p.Tags == nil {
+			p.Tags = []string{}
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
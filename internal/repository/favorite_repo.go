@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type FavoriteRepo interface {
+	Add(ctx context.Context, userID, promptID uuid.UUID) error
+	Remove(ctx context.Context, userID, promptID uuid.UUID) error
+}
+
+type favoriteRepo struct {
+	db *sqlx.DB
+}
+
+func NewFavoriteRepo(db *sqlx.DB) FavoriteRepo {
+	return &favoriteRepo{db: db}
+}
+
+// Add and Remove are idempotent; prompts.favorite_count is maintained by a
+// trigger on favorites.
+func (r *favoriteRepo) Add(ctx context.Context, userID, promptID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO favorites (user_id, prompt_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, prompt_id) DO NOTHING
+	`, userID, promptID)
+	return err
+}
+
+func (r *favoriteRepo) Remove(ctx context.Context, userID, promptID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = $1 AND prompt_id = $2`, userID, promptID)
+	return err
+}
+
+// favoritedBy is a condition matching prompts (aliased p) favorited by the
+// user bound to userArg.
+func favoritedBy(userArg string) string {
+	return "EXISTS (SELECT 1 FROM favorites f WHERE f.prompt_id = p.id AND f.user_id = " + userArg + ")"
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrIdentityInUse   = errors.New("identity is linked to another account")
+	ErrLastLoginMethod = errors.New("cannot remove the last way to sign in")
+)
+
+type IdentityRepo interface {
+	FindBySubject(ctx context.Context, provider, subject string) (models.UserIdentity, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	// CreateUser creates a passwordless user together with its first
+	// identity. The email counts as verified when the provider says so.
+	CreateUser(ctx context.Context, id models.ExternalIdentity) (models.User, error)
+	// Link attaches an identity to a user. Linking an identity the user
+	// already has is a no-op; one owned by someone else is ErrIdentityInUse.
+	Link(ctx context.Context, userID uuid.UUID, id models.ExternalIdentity) (models.UserIdentity, error)
+	TouchLogin(ctx context.Context, identityID uuid.UUID) error
+	// Unlink removes one of the user's identities. It returns sql.ErrNoRows
+	// when the identity is not theirs and ErrLastLoginMethod when the user
+	// would be left without any way to sign in.
+	Unlink(ctx context.Context, userID, identityID uuid.UUID) error
+
+	// CreateLinkIntent stores a link request and retires any the user still
+	// had, so only the latest one works.
+	CreateLinkIntent(ctx context.Context, userID uuid.UUID, provider, tokenHash string, expiresAt time.Time) error
+	// FindLinkIntent returns a live link request without using it up.
+	FindLinkIntent(ctx context.Context, tokenHash string) (models.LinkIntent, error)
+	// RedeemLinkIntent consumes a live link request. It returns
+	// sql.ErrNoRows for unknown, used or expired ones.
+	RedeemLinkIntent(ctx context.Context, tokenHash string) (models.LinkIntent, error)
+}
+
+type identityRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdentityRepo(db *sqlx.DB) IdentityRepo {
+	return &identityRepo{db: db}
+}
+
+func (r *identityRepo) FindBySubject(ctx context.Context, provider, subject string) (models.UserIdentity, error) {
+	var i models.UserIdentity
+	err := r.db.GetContext(ctx, &i, `
+		SELECT * FROM user_identities WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	return i, err
+}
+
+func (r *identityRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := r.db.SelectContext(ctx, &identities, `
+		SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at
+	`, userID)
+	return identities, err
+}
+
+func (r *identityRepo) CreateUser(ctx context.Context, id models.ExternalIdentity) (models.User, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	u := models.User{
+		ID:        uuid.New(),
+		Email:     strings.ToLower(id.Email),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if id.EmailVerified {
+		u.EmailVerifiedAt = &now
+	}
+	if _, err := tx.NamedExecContext(ctx, `
+		INSERT INTO users (id, email, password_hash, email_verified_at, created_at, updated_at)
+		VALUES (:id, :email, NULL, :email_verified_at, :created_at, :updated_at)
+	`, &u); err != nil {
+		return models.User{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`, u.ID, id.Provider, id.Subject, id.Email, now); err != nil {
+		return models.User{}, err
+	}
+	return u, tx.Commit()
+}
+
+func (r *identityRepo) Link(ctx context.Context, userID uuid.UUID, id models.ExternalIdentity) (models.UserIdentity, error) {
+	var i models.UserIdentity
+	// The conditional update leaves another user's identity untouched and
+	// returns no row.
+	err := r.db.GetContext(ctx, &i, `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (provider, subject) DO UPDATE SET email = EXCLUDED.email
+		WHERE user_identities.user_id = EXCLUDED.user_id
+		RETURNING *
+	`, userID, id.Provider, id.Subject, id.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserIdentity{}, ErrIdentityInUse
+	}
+	return i, err
+}
+
+func (r *identityRepo) TouchLogin(ctx context.Context, identityID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_identities SET last_login_at = NOW() WHERE id = $1`, identityID)
+	return err
+}
+
+func (r *identityRepo) Unlink(ctx context.Context, userID, identityID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the user serialises concurrent unlinks, which could otherwise
+	// each see another method left and remove both.
+	methods, err := loginMethodCount(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, identityID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+	return tx.Commit()
+}
+
+func (r *identityRepo) CreateLinkIntent(ctx context.Context, userID uuid.UUID, provider, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE identity_link_intents SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO identity_link_intents (id, user_id, provider, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, uuid.New(), userID, provider, tokenHash, expiresAt, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *identityRepo) FindLinkIntent(ctx context.Context, tokenHash string) (models.LinkIntent, error) {
+	var li models.LinkIntent
+	err := r.db.GetContext(ctx, &li, `
+		SELECT * FROM identity_link_intents
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`, tokenHash)
+	return li, err
+}
+
+// RedeemLinkIntent is single use for the same reason as password resets:
+// of two concurrent redemptions only one sees used_at IS NULL.
+func (r *identityRepo) RedeemLinkIntent(ctx context.Context, tokenHash string) (models.LinkIntent, error) {
+	var li models.LinkIntent
+	err := r.db.GetContext(ctx, &li, `
+		UPDATE identity_link_intents SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING *
+	`, tokenHash)
+	return li, err
+}
+
+// dropUnverifiedIdentities unlinks the providers of a user whose email is
+// not verified yet. Proving the address makes the user its owner, and
+// whoever linked a provider before that may have been someone else.
+func dropUnverifiedIdentities(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM user_identities i USING users u
+		WHERE i.user_id = u.id AND u.id = $1 AND u.email_verified_at IS NULL
+	`, userID)
+	return err
+}
+
+// loginMethodCount locks the user row and counts the ways they can sign
+// in: a password, each linked identity and each passkey.
+func loginMethodCount(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) (int, error) {
+	var n int
+	err := tx.GetContext(ctx, &n, `
+		SELECT (password_hash IS NOT NULL)::int
+			+ (SELECT COUNT(*) FROM user_identities WHERE user_id = u.id)
+			+ (SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = u.id)
+		FROM users u
+		WHERE u.id = $1
+		FOR UPDATE
+	`, userID)
+	return n, err
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type ImportRepo interface {
+	// Begin starts the single transaction an import runs in.
+	Begin(ctx context.Context, userID uuid.UUID) (ImportTx, error)
+}
+
+// ImportTx writes one user's imported prompts. Nothing is visible to other
+// requests until Commit.
+type ImportTx interface {
+	// FindByKey matches key against external IDs, then prompt IDs.
+	FindByKey(ctx context.Context, key string) (models.Prompt, error)
+	TitleExists(ctx context.Context, title string) (bool, error)
+	// EnsureCategory finds or creates the category at path, returning the
+	// leaf.
+	EnsureCategory(ctx context.Context, path []string) (uuid.NullUUID, error)
+
+	// Create inserts p with the given history; p.CurrentVersion must match
+	// the last version.
+	Create(ctx context.Context, p models.Prompt, versions []models.PromptVersion) (models.Prompt, error)
+	// Overwrite replaces an existing prompt's head and history.
+	Overwrite(ctx context.Context, p models.Prompt, versions []models.PromptVersion) (models.Prompt, error)
+	// AppendVersion writes p's content as the next revision.
+	AppendVersion(ctx context.Context, p models.Prompt, changeNote string) (models.Prompt, error)
+	SetTags(ctx context.Context, promptID uuid.UUID, names []string) error
+	SetFavorite(ctx context.Context, promptID uuid.UUID) error
+
+	Commit() error
+	Rollback() error
+}
+
+type importRepo struct {
+	db *sqlx.DB
+}
+
+func NewImportRepo(db *sqlx.DB) ImportRepo {
+	return &importRepo{db: db}
+}
+
+func (r *importRepo) Begin(ctx context.Context, userID uuid.UUID) (ImportTx, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &importTx{tx: tx, userID: userID, categories: map[string]uuid.UUID{}}, nil
+}
+
+type importTx struct {
+	tx     *sqlx.Tx
+	userID uuid.UUID
+
+	// categories caches resolved paths, keyed by their joined lower-case
+	// names.
+	categories map[string]uuid.UUID
+}
+
+func (t *importTx) FindByKey(ctx context.Context, key string) (models.Prompt, error) {
+	var p models.Prompt
+	err := t.tx.GetContext(ctx, &p, `
+		SELECT `+promptColumns+` FROM prompts p
+		WHERE p.user_id = $1 AND (p.external_id = $2 OR p.id::text = $2)
+		ORDER BY (p.external_id = $2) DESC NULLS LAST
+		LIMIT 1
+		FOR UPDATE
+	`, t.userID, key)
+	return p, err
+}
+
+func (t *importTx) TitleExists(ctx context.Context, title string) (bool, error) {
+	var exists bool
+	err := t.tx.GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM prompts WHERE user_id = $1 AND lower(title) = lower($2))
+	`, t.userID, title)
+	return exists, err
+}
+
+func (t *importTx) EnsureCategory(ctx context.Context, path []string) (uuid.NullUUID, error) {
+	var parent uuid.NullUUID
+	parentPath := "/"
+	key := ""
+	for _, name := range path {
+		key += "/" + name
+		if id, ok := t.categories[strings.ToLower(key)]; ok {
+			parent = uuid.NullUUID{UUID: id, Valid: true}
+			parentPath = ""
+			continue
+		}
+
+		var c models.Category
+		err := t.tx.GetContext(ctx, &c, `
+			SELECT * FROM categories
+			WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND lower(name) = lower($3)
+		`, t.userID, parent, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			if parentPath == "" {
+				if err := t.tx.GetContext(ctx, &parentPath, `SELECT path FROM categories WHERE id = $1`, parent.UUID); err != nil {
+					return uuid.NullUUID{}, err
+				}
+			}
+			now := time.Now()
+			c = models.Category{ID: uuid.New(), UserID: t.userID, ParentID: parent, Name: name, CreatedAt: now, UpdatedAt: now}
+			c.Path = parentPath + c.ID.String() + "/"
+			if c.Position, err = nextPosition(ctx, t.tx, t.userID, parent); err != nil {
+				return uuid.NullUUID{}, err
+			}
+			_, err = t.tx.NamedExecContext(ctx, `
+				INSERT INTO categories (id, user_id, parent_id, name, path, position, created_at, updated_at)
+				VALUES (:id, :user_id, :parent_id, :name, :path, :position, :created_at, :updated_at)
+			`, &c)
+		}
+		if err != nil {
+			return uuid.NullUUID{}, err
+		}
+
+		t.categories[strings.ToLower(key)] = c.ID
+		parent = uuid.NullUUID{UUID: c.ID, Valid: true}
+		parentPath = c.Path
+	}
+	return parent, nil
+}
+
+func (t *importTx) Create(ctx context.Context, p models.Prompt, versions []models.PromptVersion) (models.Prompt, error) {
+	now := time.Now()
+	p.ID = uuid.New()
+	p.UserID = t.userID
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+
+	if _, err := t.tx.NamedExecContext(ctx, `
+		INSERT INTO prompts (id, user_id, title, description, body, model, variables, category_id, visibility, external_id, current_version, created_at, updated_at)
+		VALUES (:id, :user_id, :title, :description, :body, :model, :variables, :category_id, :visibility, :external_id, :current_version, :created_at, :updated_at)
+	`, &p); err != nil {
+		return models.Prompt{}, err
+	}
+	return p, t.insertVersions(ctx, p.ID, versions)
+}
+
+func (t *importTx) Overwrite(ctx context.Context, p models.Prompt, versions []models.PromptVersion) (models.Prompt, error) {
+	var updated models.Prompt
+	if err := t.tx.GetContext(ctx, &updated, `
+		UPDATE prompts p
+		SET title = $3, description = $4, body = $5, model = $6, variables = $7,
+			category_id = $8, visibility = $9, current_version = $10, updated_at = $11
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+promptColumns,
+		p.ID, t.userID, p.Title, p.Description, p.Body, p.Model, p.Variables,
+		p.CategoryID, p.Visibility, p.CurrentVersion, time.Now()); err != nil {
+		return models.Prompt{}, err
+	}
+	// Versions are immutable but may be deleted along with their history.
+	if _, err := t.tx.ExecContext(ctx, `DELETE FROM prompt_versions WHERE prompt_id = $1`, p.ID); err != nil {
+		return models.Prompt{}, err
+	}
+	return updated, t.insertVersions(ctx, p.ID, versions)
+}
+
+func (t *importTx) AppendVersion(ctx context.Context, p models.Prompt, changeNote string) (models.Prompt, error) {
+	var updated models.Prompt
+	if err := t.tx.GetContext(ctx, &updated, `
+		UPDATE prompts p
+		SET title = $3, description = $4, body = $5, model = $6, variables = $7,
+			current_version = current_version + 1, updated_at = $8
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+promptColumns, p.ID, t.userID, p.Title, p.Description, p.Body, p.Model, p.Variables, time.Now()); err != nil {
+		return models.Prompt{}, err
+	}
+	return updated, insertVersion(ctx, t.tx, updated, changeNote)
+}
+
+func (t *importTx) SetTags(ctx context.Context, promptID uuid.UUID, names []string) error {
+	return setPromptTags(ctx, t.tx, t.userID, promptID, names)
+}
+
+func (t *importTx) SetFavorite(ctx context.Context, promptID uuid.UUID) error {
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO favorites (user_id, prompt_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, prompt_id) DO NOTHING
+	`, t.userID, promptID)
+	return err
+}
+
+func (t *importTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *importTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t *importTx) insertVersions(ctx context.Context, promptID uuid.UUID, versions []models.PromptVersion) error {
+	for _, v := range versions {
+		if _, err := t.tx.ExecContext(ctx, `
+			INSERT INTO prompt_versions (id, prompt_id, version, title, description, body, model, variables, author_id, change_note, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, uuid.New(), promptID, v.Version, v.Title, v.Description, v.Body, v.Model, v.Variables, t.userID, v.ChangeNote, v.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
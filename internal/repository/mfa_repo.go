@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type MFARepo interface {
+	Get(ctx context.Context, userID uuid.UUID) (models.UserMFA, error)
+	// Enroll stores a pending secret, replacing an earlier unconfirmed one.
+	// It returns sql.ErrNoRows when MFA is already enabled.
+	Enroll(ctx context.Context, userID uuid.UUID, secret string) error
+	// Confirm enables MFA, records the step of the confirming code and
+	// stores the recovery codes. It returns sql.ErrNoRows when there is no
+	// pending enrollment.
+	Confirm(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error
+	// UseStep records step as used. It reports false when that step or a
+	// later one was already accepted.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	RecoveryCodesRemaining(ctx context.Context, userID uuid.UUID) (int, error)
+	Disable(ctx context.Context, userID uuid.UUID) error
+	// ClaimAttempt counts a code check against the user before it happens.
+	// The attempt that reaches maxFailures starts a lockout of the given
+	// length. While locked it reports false and when the lockout ends.
+	ClaimAttempt(ctx context.Context, userID uuid.UUID, maxFailures int, lockout time.Duration) (bool, time.Time, error)
+	// ResetAttempts clears the failure count after a correct code.
+	ResetAttempts(ctx context.Context, userID uuid.UUID) error
+
+	CreateChallenge(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// ClaimChallengeAttempt counts an attempt on a challenge that is unused,
+	// unexpired and under maxAttempts, or returns sql.ErrNoRows.
+	ClaimChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (models.MFAChallenge, error)
+	// ConsumeChallenge marks a challenge used. It returns sql.ErrNoRows when
+	// another request got there first.
+	ConsumeChallenge(ctx context.Context, id uuid.UUID) error
+}
+
+type mfaRepo struct {
+	db *sqlx.DB
+}
+
+func NewMFARepo(db *sqlx.DB) MFARepo {
+	return &mfaRepo{db: db}
+}
+
+func (r *mfaRepo) Get(ctx context.Context, userID uuid.UUID) (models.UserMFA, error) {
+	var m models.UserMFA
+	err := r.db.GetContext(ctx, &m, `SELECT * FROM user_mfa WHERE user_id = $1`, userID)
+	return m, err
+}
+
+func (r *mfaRepo) Enroll(ctx context.Context, userID uuid.UUID, secret string) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = NOW()
+		WHERE user_mfa.confirmed_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepo) Confirm(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_mfa SET confirmed_at = NOW(), last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *mfaRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa SET last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *mfaRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *mfaRepo) RecoveryCodesRemaining(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	return n, err
+}
+
+func (r *mfaRepo) Disable(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM mfa_challenges WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *mfaRepo) ClaimAttempt(ctx context.Context, userID uuid.UUID, maxFailures int, lockout time.Duration) (bool, time.Time, error) {
+	// The count restarts once a lockout begins, so each lockout is followed
+	// by a fresh set of attempts rather than by one guess at a time.
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa SET
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3) END
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= NOW())
+	`, userID, maxFailures, lockout.Seconds())
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return true, time.Time{}, nil
+	}
+	var until time.Time
+	err = r.db.GetContext(ctx, &until, `SELECT COALESCE(locked_until, NOW()) FROM user_mfa WHERE user_id = $1`, userID)
+	return false, until, err
+}
+
+func (r *mfaRepo) ResetAttempts(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1
+	`, userID)
+	return err
+}
+
+func (r *mfaRepo) CreateChallenge(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	return err
+}
+
+// ClaimChallengeAttempt spends the attempt before the code is checked, so
+// concurrent guesses cannot all pass the cap on the same count.
+func (r *mfaRepo) ClaimChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (models.MFAChallenge, error) {
+	var c models.MFAChallenge
+	err := r.db.GetContext(ctx, &c, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+		RETURNING *
+	`, tokenHash, maxAttempts)
+	return c, err
+}
+
+func (r *mfaRepo) ConsumeChallenge(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE mfa_challenges SET used_at = NOW() WHERE id = $1 AND used_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
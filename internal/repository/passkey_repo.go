@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrPasskeyInUse = errors.New("passkey is already registered")
+
+type PasskeyRepo interface {
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Passkey, error)
+	FindByCredentialID(ctx context.Context, credentialID []byte) (models.Passkey, error)
+	// Create stores a new credential. A credential ID that is already
+	// registered, to anyone, is ErrPasskeyInUse.
+	Create(ctx context.Context, p *models.Passkey) error
+	// RecordUse stores the state reported by a successful assertion.
+	RecordUse(ctx context.Context, id uuid.UUID, signCount int64, backupState, cloneWarning bool) error
+	// Delete removes one of the user's passkeys. It returns sql.ErrNoRows
+	// when the passkey is not theirs and ErrLastLoginMethod when the user
+	// would be left without any way to sign in.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+
+	CreateSession(ctx context.Context, userID *uuid.UUID, purpose, tokenHash string, data []byte, expiresAt time.Time) error
+	// ConsumeSession deletes and returns an unexpired ceremony, so each
+	// challenge can be answered once. It returns sql.ErrNoRows otherwise.
+	ConsumeSession(ctx context.Context, purpose, tokenHash string) (models.WebAuthnSession, error)
+}
+
+type passkeyRepo struct {
+	db *sqlx.DB
+}
+
+func NewPasskeyRepo(db *sqlx.DB) PasskeyRepo {
+	return &passkeyRepo{db: db}
+}
+
+func (r *passkeyRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Passkey, error) {
+	passkeys := []models.Passkey{}
+	err := r.db.SelectContext(ctx, &passkeys, `
+		SELECT * FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at
+	`, userID)
+	return passkeys, err
+}
+
+func (r *passkeyRepo) FindByCredentialID(ctx context.Context, credentialID []byte) (models.Passkey, error) {
+	var p models.Passkey
+	err := r.db.GetContext(ctx, &p, `SELECT * FROM webauthn_credentials WHERE credential_id = $1`, credentialID)
+	return p, err
+}
+
+func (r *passkeyRepo) Create(ctx context.Context, p *models.Passkey) error {
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now()
+	res, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO webauthn_credentials
+			(id, user_id, credential_id, public_key, attestation_type, transports, aaguid,
+			 sign_count, backup_eligible, backup_state, name, created_at)
+		VALUES
+			(:id, :user_id, :credential_id, :public_key, :attestation_type, :transports, :aaguid,
+			 :sign_count, :backup_eligible, :backup_state, :name, :created_at)
+		ON CONFLICT (credential_id) DO NOTHING
+	`, p)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPasskeyInUse
+	}
+	return nil
+}
+
+func (r *passkeyRepo) RecordUse(ctx context.Context, id uuid.UUID, signCount int64, backupState, cloneWarning bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webauthn_credentials
+		SET sign_count = GREATEST(sign_count, $2), backup_state = $3,
+			clone_warning = clone_warning OR $4, last_used_at = NOW()
+		WHERE id = $1
+	`, id, signCount, backupState, cloneWarning)
+	return err
+}
+
+func (r *passkeyRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	methods, err := loginMethodCount(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if methods <= 1 {
+		return ErrLastLoginMethod
+	}
+	return tx.Commit()
+}
+
+func (r *passkeyRepo) CreateSession(ctx context.Context, userID *uuid.UUID, purpose, tokenHash string, data []byte, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webauthn_sessions (user_id, purpose, token_hash, data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, purpose, tokenHash, data, expiresAt)
+	return err
+}
+
+func (r *passkeyRepo) ConsumeSession(ctx context.Context, purpose, tokenHash string) (models.WebAuthnSession, error) {
+	var s models.WebAuthnSession
+	err := r.db.GetContext(ctx, &s, `
+		DELETE FROM webauthn_sessions
+		WHERE purpose = $1 AND token_hash = $2 AND expires_at > NOW()
+		RETURNING *
+	`, purpose, tokenHash)
+	return s, err
+}
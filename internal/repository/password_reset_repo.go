@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type PasswordResetRepo interface {
+	// Create stores a new token and retires any the user still had, so only
+	// the latest emailed link works.
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// FindUser returns the user a live token belongs to without using it
+	// up, or sql.ErrNoRows.
+	FindUser(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	// Redeem consumes a live token, sets the new password and signs the
+	// user out in one transaction: their refresh tokens are revoked and
+	// their personal access tokens deleted. Providers linked to an
+	// unverified account are unlinked. It returns sql.ErrNoRows for
+	// unknown, used or expired tokens.
+	Redeem(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error)
+}
+
+type passwordResetRepo struct {
+	db *sqlx.DB
+}
+
+func NewPasswordResetRepo(db *sqlx.DB) PasswordResetRepo {
+	return &passwordResetRepo{db: db}
+}
+
+func (r *passwordResetRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, uuid.New(), userID, tokenHash, expiresAt, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *passwordResetRepo) FindUser(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.GetContext(ctx, &userID, `
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`, tokenHash)
+	return userID, err
+}
+
+func (r *passwordResetRepo) Redeem(ctx context.Context, tokenHash, passwordHash string) (uuid.UUID, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	// The conditional update is what makes a token single-use: of two
+	// concurrent redemptions only one sees used_at IS NULL.
+	var userID uuid.UUID
+	if err := tx.GetContext(ctx, &userID, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash); err != nil {
+		return uuid.Nil, err
+	}
+	if err := dropUnverifiedIdentities(ctx, tx, userID); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1
+	`, userID, passwordHash); err != nil {
+		return uuid.Nil, err
+	}
+	// Signing out in the same transaction means a reset can never leave
+	// the old credentials working.
+	for _, q := range []string{
+		`UPDATE refresh_tokens SET is_revoked = TRUE WHERE user_id = $1 AND is_revoked = FALSE`,
+		`DELETE FROM personal_access_tokens WHERE user_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return userID, tx.Commit()
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type PersonalTokenRepo interface {
+	Create(ctx context.Context, t *models.PersonalAccessToken) error
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	// FindActive returns an unexpired token by hash, or sql.ErrNoRows.
+	FindActive(ctx context.Context, tokenHash string) (models.PersonalAccessToken, error)
+	// TouchLastUsed records a use, at most once per interval.
+	TouchLastUsed(ctx context.Context, id uuid.UUID, interval time.Duration) error
+	// Delete returns sql.ErrNoRows when the token is not the user's.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type personalTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewPersonalTokenRepo(db *sqlx.DB) PersonalTokenRepo {
+	return &personalTokenRepo{db: db}
+}
+
+func (r *personalTokenRepo) Create(ctx context.Context, t *models.PersonalAccessToken) error {
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO personal_access_tokens (id, user_id, name, token_hash, prefix, scopes, expires_at, created_at)
+		VALUES (:id, :user_id, :name, :token_hash, :prefix, :scopes, :expires_at, :created_at)
+	`, t)
+	return err
+}
+
+func (r *personalTokenRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	tokens := []models.PersonalAccessToken{}
+	err := r.db.SelectContext(ctx, &tokens, `
+		SELECT * FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	return tokens, err
+}
+
+func (r *personalTokenRepo) FindActive(ctx context.Context, tokenHash string) (models.PersonalAccessToken, error) {
+	var t models.PersonalAccessToken
+	err := r.db.GetContext(ctx, &t, `
+		SELECT * FROM personal_access_tokens WHERE token_hash = $1 AND expires_at > NOW()
+	`, tokenHash)
+	return t, err
+}
+
+func (r *personalTokenRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, interval time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))
+	`, id, interval.Seconds())
+	return err
+}
+
+func (r *personalTokenRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
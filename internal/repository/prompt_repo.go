@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type PromptRepo interface {
+	Create(ctx context.Context, p models.Prompt, changeNote string) (models.Prompt, error)
+	FindByID(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error)
+	FindReadable(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error)
+	FindShared(ctx context.Context, id uuid.UUID) (models.Prompt, error)
+	List(ctx context.Context, userID uuid.UUID, params models.PromptListParams) ([]models.Prompt, int, error)
+	// BeginEdit locks one of the user's prompts and starts the transaction
+	// an update runs in. It returns sql.ErrNoRows when the prompt is not
+	// theirs.
+	BeginEdit(ctx context.Context, userID, id uuid.UUID) (PromptEditTx, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+
+	ListVersions(ctx context.Context, userID, promptID uuid.UUID) ([]models.PromptVersion, error)
+	FindVersion(ctx context.Context, userID, promptID uuid.UUID, version int) (models.PromptVersion, error)
+
+	ListTemplates(ctx context.Context, userID uuid.UUID) ([]models.Prompt, error)
+}
+
+// PromptEditTx changes one prompt. Nothing is visible to other requests
+// until Commit.
+type PromptEditTx interface {
+	SetTags(ctx context.Context, names []string) error
+	SetCategory(ctx context.Context, categoryID uuid.NullUUID) error
+	SetVisibility(ctx context.Context, visibility string) error
+	// AppendVersion writes p's content as the new head and next revision.
+	AppendVersion(ctx context.Context, p models.Prompt, changeNote string) error
+
+	Commit() error
+	Rollback() error
+}
+
+// promptColumns lists the prompt columns scanned into models.Prompt. The
+// generated search_vector column is deliberately left out.
+const promptColumns = `
+	p.id, p.user_id, p.title, p.description, p.body, p.model, p.variables,
+	p.category_id, p.visibility, p.external_id, p.current_version, p.favorite_count, p.created_at, p.updated_at
+`
+
+// readableBy is a condition matching prompts (aliased p) the user bound to
+// userArg may read: their own plus anything not private.
+func readableBy(userArg string) string {
+	return "(p.user_id = " + userArg + " OR p.visibility IN ('public', 'unlisted'))"
+}
+
+type promptRepo struct {
+	db *sqlx.DB
+}
+
+func NewPromptRepo(db *sqlx.DB) PromptRepo {
+	return &promptRepo{db: db}
+}
+
+func (r *promptRepo) Create(ctx context.Context, p models.Prompt, changeNote string) (models.Prompt, error) {
+	now := time.Now()
+	p.ID = uuid.New()
+	p.CurrentVersion = 1
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, `
+		INSERT INTO prompts (id, user_id, title, description, body, model, variables, category_id, visibility, current_version, created_at, updated_at)
+		VALUES (:id, :user_id, :title, :description, :body, :model, :variables, :category_id, :visibility, :current_version, :created_at, :updated_at)
+	`, &p); err != nil {
+		return models.Prompt{}, err
+	}
+	if err := insertVersion(ctx, tx, p, changeNote); err != nil {
+		return models.Prompt{}, err
+	}
+	if len(p.Tags) > 0 {
+		if err := setPromptTags(ctx, tx, p.UserID, p.ID, p.Tags); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+
+	return p, tx.Commit()
+}
+
+func (r *promptRepo) FindByID(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	var p models.Prompt
+	err := r.db.GetContext(ctx, &p, `
+		SELECT `+promptColumns+`, `+favoritedBy("$2")+` AS favorited
+		FROM prompts p WHERE p.id = $1 AND p.user_id = $2
+	`, id, userID)
+	return p, err
+}
+
+func (r *promptRepo) FindReadable(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	var p models.Prompt
+	err := r.db.GetContext(ctx, &p, `
+		SELECT `+promptColumns+`, `+favoritedBy("$2")+` AS favorited
+		FROM prompts p WHERE p.id = $1 AND `+readableBy("$2"), id, userID)
+	return p, err
+}
+
+// FindShared loads a prompt regardless of owner; callers must have already
+// authorised access through a share link.
+func (r *promptRepo) FindShared(ctx context.Context, id uuid.UUID) (models.Prompt, error) {
+	var p models.Prompt
+	err := r.db.GetContext(ctx, &p, `SELECT `+promptColumns+` FROM prompts p WHERE p.id = $1`, id)
+	return p, err
+}
+
+func (r *promptRepo) List(ctx context.Context, userID uuid.UUID, params models.PromptListParams) ([]models.Prompt, int, error) {
+	var w whereBuilder
+	uid := w.arg(userID)
+	if params.FavoritesOnly {
+		// Favorites may belong to other users; they stay listed while readable.
+		w.add(readableBy(uid))
+		w.add(favoritedBy(uid))
+	} else {
+		w.add("p.user_id = " + uid)
+	}
+	if params.CategoryID.Valid {
+		if params.IncludeDescendants {
+			w.add(`p.category_id IN (
+				SELECT d.id FROM categories c
+				JOIN categories d ON d.user_id = c.user_id AND d.path LIKE c.path || '%'
+				WHERE c.id = ` + w.arg(params.CategoryID.UUID) + `)`)
+		} else {
+			w.add("p.category_id = " + w.arg(params.CategoryID.UUID))
+		}
+	}
+	if params.Search != nil {
+		applySearchFilters(&w, uid, *params.Search)
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM prompts p WHERE `+w.sql(), w.args...); err != nil {
+		return nil, 0, err
+	}
+
+	prompts := []models.Prompt{}
+	query := `SELECT ` + promptColumns + `, ` + favoritedBy(uid) + ` AS favorited FROM prompts p WHERE ` + w.sql() +
+		` ORDER BY p.updated_at DESC, p.id DESC LIMIT ` + w.arg(params.Limit) + ` OFFSET ` + w.arg(params.Offset)
+	err := r.db.SelectContext(ctx, &prompts, query, w.args...)
+	return prompts, total, err
+}
+
+func (r *promptRepo) BeginEdit(ctx context.Context, userID, id uuid.UUID) (PromptEditTx, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The row lock serialises concurrent edits so version numbers never
+	// collide.
+	var locked uuid.UUID
+	if err := tx.GetContext(ctx, &locked, `
+		SELECT id FROM prompts WHERE id = $1 AND user_id = $2 FOR UPDATE
+	`, id, userID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &promptEditTx{tx: tx, userID: userID, id: id}, nil
+}
+
+func (r *promptRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM prompts WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *promptRepo) ListVersions(ctx context.Context, userID, promptID uuid.UUID) ([]models.PromptVersion, error) {
+	versions := []models.PromptVersion{}
+	err := r.db.SelectContext(ctx, &versions, `
+		SELECT v.* FROM prompt_versions v
+		JOIN prompts p ON p.id = v.prompt_id
+		WHERE v.prompt_id = $1 AND p.user_id = $2
+		ORDER BY v.version DESC
+	`, promptID, userID)
+	return versions, err
+}
+
+func (r *promptRepo) FindVersion(ctx context.Context, userID, promptID uuid.UUID, version int) (models.PromptVersion, error) {
+	var v models.PromptVersion
+	err := r.db.GetContext(ctx, &v, `
+		SELECT v.* FROM prompt_versions v
+		JOIN prompts p ON p.id = v.prompt_id
+		WHERE v.prompt_id = $1 AND p.user_id = $2 AND v.version = $3
+	`, promptID, userID, version)
+	return v, err
+}
+
+func (r *promptRepo) ListTemplates(ctx context.Context, userID uuid.UUID) ([]models.Prompt, error) {
+	prompts := []models.Prompt{}
+	err := r.db.SelectContext(ctx, &prompts, `
+		SELECT `+promptColumns+` FROM prompts p
+		WHERE p.user_id = $1 AND p.variables <> '[]'::jsonb
+		ORDER BY p.updated_at DESC, p.id DESC
+	`, userID)
+	return prompts, err
+}
+
+type promptEditTx struct {
+	tx     *sqlx.Tx
+	userID uuid.UUID
+	id     uuid.UUID
+}
+
+func (t *promptEditTx) SetTags(ctx context.Context, names []string) error {
+	return setPromptTags(ctx, t.tx, t.userID, t.id, names)
+}
+
+func (t *promptEditTx) SetCategory(ctx context.Context, categoryID uuid.NullUUID) error {
+	_, err := t.tx.ExecContext(ctx, `UPDATE prompts SET category_id = $2 WHERE id = $1`, t.id, categoryID)
+	return err
+}
+
+func (t *promptEditTx) SetVisibility(ctx context.Context, visibility string) error {
+	_, err := t.tx.ExecContext(ctx, `UPDATE prompts SET visibility = $2 WHERE id = $1`, t.id, visibility)
+	return err
+}
+
+func (t *promptEditTx) AppendVersion(ctx context.Context, p models.Prompt, changeNote string) error {
+	var updated models.Prompt
+	if err := t.tx.GetContext(ctx, &updated, `
+		UPDATE prompts p
+		SET title = $2, description = $3, body = $4, model = $5, variables = $6,
+			current_version = current_version + 1, updated_at = $7
+		WHERE id = $1
+		RETURNING `+promptColumns, t.id, p.Title, p.Description, p.Body, p.Model, p.Variables, time.Now()); err != nil {
+		return err
+	}
+	return insertVersion(ctx, t.tx, updated, changeNote)
+}
+
+func (t *promptEditTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *promptEditTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func insertVersion(ctx context.Context, tx *sqlx.Tx, p models.Prompt, changeNote string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO prompt_versions (id, prompt_id, version, title, description, body, model, variables, author_id, change_note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, uuid.New(), p.ID, p.CurrentVersion, p.Title, p.Description, p.Body, p.Model, p.Variables, p.UserID, changeNote, p.UpdatedAt)
+	return err
+}
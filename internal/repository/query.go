@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// whereBuilder accumulates AND-ed conditions with positional arguments for
+// queries whose filters are optional.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+// arg registers v and returns its placeholder.
+func (w *whereBuilder) arg(v any) string {
+	w.args = append(w.args, v)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *whereBuilder) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+func (w *whereBuilder) sql() string {
+	if len(w.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(w.conds, " AND ")
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// RevocationRepo is the Postgres revocation store, shared by every API
+// instance.
+type RevocationRepo interface {
+	Revoke(ctx context.Context, id string, until time.Time) error
+	RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, t time.Time) error
+	Revoked(ctx context.Context, userID uuid.UUID, issuedAt time.Time, ids ...string) (bool, error)
+}
+
+type revocationRepo struct {
+	db *sqlx.DB
+}
+
+func NewRevocationRepo(db *sqlx.DB) RevocationRepo {
+	return &revocationRepo{db: db}
+}
+
+func (r *revocationRepo) Revoke(ctx context.Context, id string, until time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Entries are only needed while a token they match could still be
+	// valid, so revoking is also when old ones are cleared out.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO revoked_access_tokens (id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET expires_at = GREATEST(revoked_access_tokens.expires_at, EXCLUDED.expires_at)
+	`, id, until); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *revocationRepo) RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, t time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO access_token_watermarks (user_id, not_before) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET not_before = GREATEST(access_token_watermarks.not_before, EXCLUDED.not_before)
+	`, userID, t)
+	return err
+}
+
+func (r *revocationRepo) Revoked(ctx context.Context, userID uuid.UUID, issuedAt time.Time, ids ...string) (bool, error) {
+	var revoked bool
+	err := r.db.GetContext(ctx, &revoked, `
+		SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE id = ANY($2) AND expires_at > NOW())
+			OR EXISTS (SELECT 1 FROM access_token_watermarks WHERE user_id = $1 AND not_before > $3)
+	`, userID, pq.Array(ids), issuedAt)
+	return revoked, err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type SavedSearchRepo interface {
+	Create(ctx context.Context, userID uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error)
+	FindByID(ctx context.Context, userID, id uuid.UUID) (models.SavedSearch, error)
+	List(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error)
+	Update(ctx context.Context, userID, id uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type savedSearchRepo struct {
+	db *sqlx.DB
+}
+
+func NewSavedSearchRepo(db *sqlx.DB) SavedSearchRepo {
+	return &savedSearchRepo{db: db}
+}
+
+func (r *savedSearchRepo) Create(ctx context.Context, userID uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error) {
+	now := time.Now()
+	s := models.SavedSearch{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Filters:   filters,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO saved_searches (id, user_id, name, filters, created_at, updated_at)
+		VALUES (:id, :user_id, :name, :filters, :created_at, :updated_at)
+	`, &s)
+	return s, err
+}
+
+func (r *savedSearchRepo) FindByID(ctx context.Context, userID, id uuid.UUID) (models.SavedSearch, error) {
+	var s models.SavedSearch
+	err := r.db.GetContext(ctx, &s, `SELECT * FROM saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	return s, err
+}
+
+func (r *savedSearchRepo) List(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error) {
+	searches := []models.SavedSearch{}
+	err := r.db.SelectContext(ctx, &searches, `
+		SELECT * FROM saved_searches WHERE user_id = $1 ORDER BY name
+	`, userID)
+	return searches, err
+}
+
+func (r *savedSearchRepo) Update(ctx context.Context, userID, id uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error) {
+	var s models.SavedSearch
+	err := r.db.GetContext(ctx, &s, `
+		UPDATE saved_searches SET name = $3, filters = $4, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`, id, userID, name, filters)
+	return s, err
+}
+
+func (r *savedSearchRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchHistoryRepo interface {
+	Add(ctx context.Context, userID uuid.UUID, filters models.SearchFilters, keep int) error
+	List(ctx context.Context, userID uuid.UUID, limit int) ([]models.SearchHistoryEntry, error)
+	FindByID(ctx context.Context, userID, id uuid.UUID) (models.SearchHistoryEntry, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	Clear(ctx context.Context, userID uuid.UUID) error
+}
+
+type searchHistoryRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchHistoryRepo(db *sqlx.DB) SearchHistoryRepo {
+	return &searchHistoryRepo{db: db}
+}
+
+// Add records a search and trims the user's history to the newest keep
+// entries.
+func (r *searchHistoryRepo) Add(ctx context.Context, userID uuid.UUID, filters models.SearchFilters, keep int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO search_history (id, user_id, filters, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, uuid.New(), userID, filters, time.Now()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM search_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM search_history WHERE user_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		)
+	`, userID, keep); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *searchHistoryRepo) List(ctx context.Context, userID uuid.UUID, limit int) ([]models.SearchHistoryEntry, error) {
+	entries := []models.SearchHistoryEntry{}
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT * FROM search_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	return entries, err
+}
+
+func (r *searchHistoryRepo) FindByID(ctx context.Context, userID, id uuid.UUID) (models.SearchHistoryEntry, error) {
+	var e models.SearchHistoryEntry
+	err := r.db.GetContext(ctx, &e, `SELECT * FROM search_history WHERE id = $1 AND user_id = $2`, id, userID)
+	return e, err
+}
+
+func (r *searchHistoryRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM search_history WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *searchHistoryRepo) Clear(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM search_history WHERE user_id = $1`, userID)
+	return err
+}
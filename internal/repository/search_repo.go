@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Highlight markers wrapped around matched terms by ts_headline. They are
+// control characters so the service can escape the text before turning
+// them into markup.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+type SearchRepo interface {
+	Search(ctx context.Context, userID uuid.UUID, params models.SearchParams) ([]models.SearchResult, error)
+}
+
+type searchRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchRepo(db *sqlx.DB) SearchRepo {
+	return &searchRepo{db: db}
+}
+
+type searchRow struct {
+	models.Prompt
+	Rank           float32 `db:"rank"`
+	TitleHighlight string  `db:"title_highlight"`
+	Snippet        string  `db:"snippet"`
+}
+
+// Search returns up to params.Limit matches ordered by rank, then recency.
+// Without a text query every prompt matching the filters ranks equally.
+func (r *searchRepo) Search(ctx context.Context, userID uuid.UUID, params models.SearchParams) ([]models.SearchResult, error) {
+	f := params.Filters
+
+	var w whereBuilder
+	uid := w.arg(userID)
+	w.add("p.user_id = " + uid)
+
+	rank := "0::real"
+	titleHighlight := "m.title"
+	snippet := "left(m.body, 240)"
+	if f.Query != "" {
+		query := "websearch_to_tsquery('english', " + w.arg(f.Query) + ")"
+		marks := "StartSel=" + HighlightStart + ",StopSel=" + HighlightStop
+		rank = "ts_rank(p.search_vector, " + query + ")"
+		titleHighlight = "ts_headline('english', m.title, " + query + ", " + w.arg(marks+",HighlightAll=true") + ")"
+		snippet = "ts_headline('english', m.body, " + query + ", " + w.arg(marks+",MaxFragments=2,MaxWords=30,MinWords=10") + ")"
+	}
+
+	applySearchFilters(&w, uid, f)
+
+	matches := `SELECT ` + promptColumns + `, ` + favoritedBy(uid) + ` AS favorited, ` + rank + ` AS rank
+		FROM prompts p WHERE ` + w.sql()
+
+	page := "TRUE"
+	if params.After != nil {
+		page = "(m.rank, m.updated_at, m.id) < (" + w.arg(params.After.Rank) + "::real, " +
+			w.arg(params.After.UpdatedAt) + "::timestamptz, " + w.arg(params.After.ID) + "::uuid)"
+	}
+
+	// Highlights are computed on the outer query so ts_headline only runs
+	// for the rows on this page.
+	query := `
+		SELECT m.*, ` + titleHighlight + ` AS title_highlight, ` + snippet + ` AS snippet
+		FROM (` + matches + `) m
+		WHERE ` + page + `
+		ORDER BY m.rank DESC, m.updated_at DESC, m.id DESC
+		LIMIT ` + w.arg(params.Limit)
+
+	var rows []searchRow
+	if err := r.db.SelectContext(ctx, &rows, query, w.args...); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = models.SearchResult{
+			Prompt:         row.Prompt,
+			Rank:           row.Rank,
+			TitleHighlight: row.TitleHighlight,
+			Snippet:        row.Snippet,
+		}
+	}
+	return results, nil
+}
+
+// applySearchFilters adds the conditions for f to w. Queries using it must
+// alias prompts as p; userArg is the placeholder bound to the searching user.
+func applySearchFilters(w *whereBuilder, userArg string, f models.SearchFilters) {
+	if f.Query != "" {
+		w.add("p.search_vector @@ websearch_to_tsquery('english', " + w.arg(f.Query) + ")")
+	}
+	if f.CategoryID.Valid {
+		w.add(`p.category_id IN (
+			SELECT d.id FROM categories c
+			JOIN categories d ON d.user_id = c.user_id AND d.path LIKE c.path || '%'
+			WHERE c.id = ` + w.arg(f.CategoryID.UUID) + ` AND c.user_id = p.user_id)`)
+	}
+	if len(f.Tags) > 0 {
+		w.add(`p.id IN (
+			SELECT pt.prompt_id FROM prompt_tags pt
+			JOIN tags t ON t.id = pt.tag_id
+			WHERE t.user_id = p.user_id AND t.name = ANY(` + w.arg(pq.Array(f.Tags)) + `::citext[])
+			GROUP BY pt.prompt_id
+			HAVING COUNT(DISTINCT t.id) = ` + w.arg(len(f.Tags)) + `)`)
+	}
+	if f.CreatedAfter != nil {
+		w.add("p.created_at >= " + w.arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		w.add("p.created_at < " + w.arg(*f.CreatedBefore))
+	}
+	if f.Model != "" {
+		w.add("lower(p.model) = lower(" + w.arg(f.Model) + ")")
+	}
+	if f.Visibility != "" {
+		w.add("p.visibility = " + w.arg(f.Visibility))
+	}
+	if f.Favorite {
+		w.add(favoritedBy(userArg))
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type SecurityEventRepo interface {
+	Record(ctx context.Context, e *models.SecurityEvent) error
+}
+
+type securityEventRepo struct {
+	db *sqlx.DB
+}
+
+func NewSecurityEventRepo(db *sqlx.DB) SecurityEventRepo {
+	return &securityEventRepo{db: db}
+}
+
+func (r *securityEventRepo) Record(ctx context.Context, e *models.SecurityEvent) error {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO security_events (id, user_id, type, ip, user_agent, details, created_at)
+		VALUES (:id, :user_id, :type, :ip, :user_agent, :details, :created_at)
+	`, e)
+	return err
+}
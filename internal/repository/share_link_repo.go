@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type ShareLinkRepo interface {
+	Create(ctx context.Context, l models.ShareLink) (models.ShareLink, error)
+	ListForPrompt(ctx context.Context, userID, promptID uuid.UUID) ([]models.ShareLink, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (models.ShareLink, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) (models.ShareLink, error)
+	RecordView(ctx context.Context, id uuid.UUID) error
+	ClaimPasswordAttempt(ctx context.Context, id uuid.UUID, window time.Duration) (int, time.Time, error)
+	ResetPasswordAttempts(ctx context.Context, id uuid.UUID) error
+}
+
+type shareLinkRepo struct {
+	db *sqlx.DB
+}
+
+func NewShareLinkRepo(db *sqlx.DB) ShareLinkRepo {
+	return &shareLinkRepo{db: db}
+}
+
+func (r *shareLinkRepo) Create(ctx context.Context, l models.ShareLink) (models.ShareLink, error) {
+	l.ID = uuid.New()
+	l.CreatedAt = time.Now()
+
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO share_links (id, prompt_id, user_id, token_hash, password_hash, expires_at, created_at)
+		VALUES (:id, :prompt_id, :user_id, :token_hash, :password_hash, :expires_at, :created_at)
+	`, &l)
+	return l, err
+}
+
+func (r *shareLinkRepo) ListForPrompt(ctx context.Context, userID, promptID uuid.UUID) ([]models.ShareLink, error) {
+	links := []models.ShareLink{}
+	err := r.db.SelectContext(ctx, &links, `
+		SELECT * FROM share_links
+		WHERE prompt_id = $1 AND user_id = $2
+		ORDER BY created_at DESC
+	`, promptID, userID)
+	return links, err
+}
+
+func (r *shareLinkRepo) FindByTokenHash(ctx context.Context, tokenHash string) (models.ShareLink, error) {
+	var l models.ShareLink
+	err := r.db.GetContext(ctx, &l, `SELECT * FROM share_links WHERE token_hash = $1`, tokenHash)
+	return l, err
+}
+
+// Revoke is idempotent: revoking an already revoked link keeps the original
+// revocation time.
+func (r *shareLinkRepo) Revoke(ctx context.Context, userID, id uuid.UUID) (models.ShareLink, error) {
+	var l models.ShareLink
+	err := r.db.GetContext(ctx, &l, `
+		UPDATE share_links SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`, id, userID)
+	return l, err
+}
+
+// RecordView counts a view only while the link is still usable, so a link
+// revoked or expiring concurrently is never counted; it returns
+// sql.ErrNoRows in that case.
+func (r *shareLinkRepo) RecordView(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE share_links SET view_count = view_count + 1, last_viewed_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimPasswordAttempt counts a password guess before it is checked, so
+// concurrent guesses cannot slip past the limit. A window starts with the
+// first guess after the previous one ended. It returns the guesses made in
+// the current window, this one included, and when the window ends.
+func (r *shareLinkRepo) ClaimPasswordAttempt(ctx context.Context, id uuid.UUID, window time.Duration) (int, time.Time, error) {
+	var res struct {
+		Attempts int       `db:"password_attempts"`
+		Ends     time.Time `db:"window_ends"`
+	}
+	err := r.db.GetContext(ctx, &res, `
+		UPDATE share_links SET
+			password_attempts = CASE
+				WHEN password_window_start IS NULL OR password_window_start <= NOW() - make_interval(secs => $2) THEN 1
+				ELSE password_attempts + 1 END,
+			password_window_start = CASE
+				WHEN password_window_start IS NULL OR password_window_start <= NOW() - make_interval(secs => $2) THEN NOW()
+				ELSE password_window_start END
+		WHERE id = $1
+		RETURNING password_attempts, password_window_start + make_interval(secs => $2) AS window_ends
+	`, id, window.Seconds())
+	return res.Attempts, res.Ends, err
+}
+
+// ResetPasswordAttempts clears the count after a correct password.
+func (r *shareLinkRepo) ResetPasswordAttempts(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE share_links SET password_attempts = 0, password_window_start = NULL WHERE id = $1
+	`, id)
+	return err
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type TagRepo interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.Tag, error)
+	FindByID(ctx context.Context, userID, id uuid.UUID) (models.Tag, error)
+	Autocomplete(ctx context.Context, userID uuid.UUID, prefix string, limit int) ([]models.Tag, error)
+	Popular(ctx context.Context, userID uuid.UUID, limit int) ([]models.Tag, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Tag, error)
+	Merge(ctx context.Context, userID, targetID uuid.UUID, sourceIDs []uuid.UUID) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+
+	SetPromptTags(ctx context.Context, userID, promptID uuid.UUID, names []string) error
+	TagsForPrompts(ctx context.Context, promptIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+}
+
+type tagRepo struct {
+	db *sqlx.DB
+}
+
+func NewTagRepo(db *sqlx.DB) TagRepo {
+	return &tagRepo{db: db}
+}
+
+const tagColumns = `
+	t.id, t.user_id, t.name, t.created_at,
+	(SELECT COUNT(*) FROM prompt_tags pt WHERE pt.tag_id = t.id) AS usage_count
+`
+
+func (r *tagRepo) List(ctx context.Context, userID uuid.UUID) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.SelectContext(ctx, &tags, `
+		SELECT `+tagColumns+` FROM tags t
+		WHERE t.user_id = $1
+		ORDER BY t.name
+	`, userID)
+	return tags, err
+}
+
+func (r *tagRepo) FindByID(ctx context.Context, userID, id uuid.UUID) (models.Tag, error) {
+	var t models.Tag
+	err := r.db.GetContext(ctx, &t, `
+		SELECT `+tagColumns+` FROM tags t
+		WHERE t.id = $1 AND t.user_id = $2
+	`, id, userID)
+	return t, err
+}
+
+func (r *tagRepo) Autocomplete(ctx context.Context, userID uuid.UUID, prefix string, limit int) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.SelectContext(ctx, &tags, `
+		SELECT * FROM (
+			SELECT `+tagColumns+` FROM tags t
+			WHERE t.user_id = $1 AND lower(t.name::text) LIKE lower($2::text) || '%' ESCAPE '\'
+		) t
+		ORDER BY t.usage_count DESC, t.name
+		LIMIT $3
+	`, userID, escapeLike(prefix), limit)
+	return tags, err
+}
+
+func (r *tagRepo) Popular(ctx context.Context, userID uuid.UUID, limit int) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := r.db.SelectContext(ctx, &tags, `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(pt.prompt_id) AS usage_count
+		FROM tags t
+		JOIN prompt_tags pt ON pt.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY usage_count DESC, t.name
+		LIMIT $2
+	`, userID, limit)
+	return tags, err
+}
+
+func (r *tagRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Tag, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE tags SET name = $3 WHERE id = $1 AND user_id = $2
+	`, id, userID, name); err != nil {
+		return models.Tag{}, err
+	}
+	return r.FindByID(ctx, userID, id)
+}
+
+// Merge folds every source tag into target: links are rewritten to the
+// target (skipping prompts that already carry it) and the sources are
+// removed, all in one transaction.
+func (r *tagRepo) Merge(ctx context.Context, userID, targetID uuid.UUID, sourceIDs []uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := pq.Array(uuidStrings(append([]uuid.UUID{targetID}, sourceIDs...)))
+	var owned int
+	if err := tx.GetContext(ctx, &owned, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM tags WHERE user_id = $1 AND id = ANY($2::uuid[]) FOR UPDATE
+		) t
+	`, userID, ids); err != nil {
+		return err
+	}
+	if owned != len(sourceIDs)+1 {
+		return sql.ErrNoRows
+	}
+
+	sources := pq.Array(uuidStrings(sourceIDs))
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO prompt_tags (prompt_id, tag_id)
+		SELECT prompt_id, $1 FROM prompt_tags WHERE tag_id = ANY($2::uuid[])
+		ON CONFLICT DO NOTHING
+	`, targetID, sources); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM tags WHERE user_id = $1 AND id = ANY($2::uuid[])
+	`, userID, sources); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *tagRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetPromptTags replaces the prompt's tags with names, creating any tag the
+// user does not have yet.
+func (r *tagRepo) SetPromptTags(ctx context.Context, userID, promptID uuid.UUID, names []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPromptTags(ctx, tx, userID, promptID, names); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *tagRepo) TagsForPrompts(ctx context.Context, promptIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	out := map[uuid.UUID][]string{}
+	if len(promptIDs) == 0 {
+		return out, nil
+	}
+
+	var rows []struct {
+		PromptID uuid.UUID `db:"prompt_id"`
+		Name     string    `db:"name"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `
+		SELECT pt.prompt_id, t.name FROM prompt_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.prompt_id = ANY($1::uuid[])
+		ORDER BY t.name
+	`, pq.Array(uuidStrings(promptIDs))); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		out[row.PromptID] = append(out[row.PromptID], row.Name)
+	}
+	return out, nil
+}
+
+func setPromptTags(ctx context.Context, tx *sqlx.Tx, userID, promptID uuid.UUID, names []string) error {
+	tagIDs := make([]uuid.UUID, 0, len(names))
+	for _, name := range names {
+		var id uuid.UUID
+		// The no-op update makes RETURNING yield the existing row on conflict.
+		if err := tx.GetContext(ctx, &id, `
+			INSERT INTO tags (id, user_id, name, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, name) DO UPDATE SET name = tags.name
+			RETURNING id
+		`, uuid.New(), userID, name, time.Now()); err != nil {
+			return err
+		}
+		tagIDs = append(tagIDs, id)
+	}
+
+	ids := pq.Array(uuidStrings(tagIDs))
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM prompt_tags WHERE prompt_id = $1 AND NOT (tag_id = ANY($2::uuid[]))
+	`, promptID, ids); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO prompt_tags (prompt_id, tag_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT DO NOTHING
+	`, promptID, ids)
+	return err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/congdv/go-auth/api/internal/models"
@@ -12,8 +13,22 @@ import (
 type TokenRepo interface {
 	Insert(ctx context.Context, t models.RefreshToken) error
 	FindByJTI(ctx context.Context, jti uuid.UUID) (models.RefreshToken, error)
+	// Rotate revokes the token with oldJTI and inserts next as its
+	// successor. The check and the revoke are one statement, so of two
+	// concurrent rotations only one wins; the other gets sql.ErrNoRows, as
+	// does a token that was already revoked.
+	Rotate(ctx context.Context, oldJTI uuid.UUID, next models.RefreshToken) error
 	RevokeByJTI(ctx context.Context, jti uuid.UUID) error
 	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+
+	// ListSessions returns the user's sessions that still hold a live
+	// refresh token, most recently used first.
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	// RevokeSession returns sql.ErrNoRows when the session is not the
+	// user's or has already ended.
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	// RevokeOtherSessions returns the IDs of the sessions it ended.
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type tokenRepo struct {
@@ -25,10 +40,14 @@ func NewTokenRepo(db *sqlx.DB) TokenRepo {
 }
 
 func (r *tokenRepo) Insert(ctx context.Context, t models.RefreshToken) error {
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO refresh_tokens (id, user_id, jti, is_revoked, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, t.ID, t.UserID, t.JTI, t.IsRevoked, t.ExpiresAt, time.Now())
+	return insertToken(ctx, r.db, t)
+}
+
+func insertToken(ctx context.Context, db sqlx.ExecerContext, t models.RefreshToken) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, jti, session_id, parent_jti, is_revoked, user_agent, ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, t.ID, t.UserID, t.JTI, t.SessionID, t.ParentJTI, t.IsRevoked, t.UserAgent, t.IP, t.ExpiresAt, time.Now())
 	return err
 }
 
@@ -41,6 +60,29 @@ func (r *tokenRepo) FindByJTI(ctx context.Context, jti uuid.UUID) (models.Refres
 	return t, err
 }
 
+func (r *tokenRepo) Rotate(ctx context.Context, oldJTI uuid.UUID, next models.RefreshToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET is_revoked = TRUE, replaced_by = $2, last_used_at = NOW()
+		WHERE jti = $1 AND is_revoked = FALSE AND expires_at > NOW()
+	`, oldJTI, next.JTI)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := insertToken(ctx, tx, next); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (r *tokenRepo) RevokeByJTI(ctx context.Context, jti uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE refresh_tokens SET is_revoked = TRUE WHERE jti = $1
@@ -56,3 +98,39 @@ func (r *tokenRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) erro
 
 	return err
 }
+
+func (r *tokenRepo) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := r.db.SelectContext(ctx, &sessions, `
+		SELECT t.session_id AS id, t.user_agent, t.ip, t.created_at AS last_used_at, t.expires_at,
+			(SELECT MIN(s.created_at) FROM refresh_tokens s WHERE s.session_id = t.session_id) AS created_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.is_revoked = FALSE AND t.expires_at > NOW()
+		ORDER BY t.created_at DESC
+	`, userID)
+	return sessions, err
+}
+
+func (r *tokenRepo) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET is_revoked = TRUE
+		WHERE user_id = $1 AND session_id = $2 AND is_revoked = FALSE
+	`, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *tokenRepo) RevokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	err := r.db.SelectContext(ctx, &ids, `
+		UPDATE refresh_tokens SET is_revoked = TRUE
+		WHERE user_id = $1 AND session_id <> $2 AND is_revoked = FALSE AND expires_at > NOW()
+		RETURNING session_id
+	`, userID, keepSessionID)
+	return ids, err
+}
@@ -13,11 +13,13 @@ import (
 
 type UserRepo interface {
 	Create(ctx context.Context, email, passwordHash string) (models.User, error)
-	CreateOAuth(ctx context.Context, email, provider, providerID string) (models.User, error)
 	FindByEmail(ctx context.Context, email string) (models.User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
 	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
 	AddRole(ctx context.Context, userId uuid.UUID, roleName string) error
+	// RemoveRole returns sql.ErrNoRows when the user did not have the role.
+	RemoveRole(ctx context.Context, userID uuid.UUID, roleName string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) (models.User, error)
 }
 
 type userRepo struct {
@@ -38,31 +40,12 @@ func (r *userRepo) Create(ctx context.Context, email, passwordHash string) (mode
 		UpdatedAt:    now,
 	}
 	_, err := r.db.NamedExecContext(ctx, `
-			INSERT INTO users (id, email, password_hash, provider, provider_id, created_at, updated_at)
-			VALUES (:id, :email, :password_hash, NULL, NULL, :created_at, :updated_at)
+			INSERT INTO users (id, email, password_hash, created_at, updated_at)
+			VALUES (:id, :email, :password_hash, :created_at, :updated_at)
 	`, &u)
 	return u, err
 }
 
-func (r *userRepo) CreateOAuth(ctx context.Context, email, provider, providerID string) (models.User, error) {
-	now := time.Now()
-	u := models.User{
-		ID:         uuid.New(),
-		Email:      strings.ToLower(email),
-		Provider:   sql.NullString{String: provider, Valid: true},
-		ProviderID: sql.NullString{String: providerID, Valid: true},
-		CreatedAt:  now,
-		UpdatedAt:  now,
-	}
-
-	_, err := r.db.NamedExecContext(ctx, `
-			INSERT INTO users (id, email, password_hash, provider, provider_id, created_at, updated_at)
-				VALUES (:id, :email, NULL, :provider, :provider_id, :created_at, :updated_at)
-	`, &u)
-
-	return u, err
-}
-
 func (r *userRepo) FindByEmail(ctx context.Context, email string) (models.User, error) {
 	var u models.User
 	err := r.db.GetContext(ctx, &u, `SELECT * FROM users WHERE email = $1`, email)
@@ -99,3 +82,28 @@ func (r *userRepo) AddRole(ctx context.Context, userId uuid.UUID, roleName strin
 	`, userId, roleId)
 	return err
 }
+
+func (r *userRepo) RemoveRole(ctx context.Context, userID uuid.UUID, roleName string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM user_roles ur
+		USING roles r
+		WHERE ur.role_id = r.id AND ur.user_id = $1 AND r.name = $2
+	`, userID, roleName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID) (models.User, error) {
+	var u models.User
+	err := r.db.GetContext(ctx, &u, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, id)
+	return u, err
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/jwtkeys"
 	"github.com/congdv/go-auth/api/internal/models"
 	"github.com/congdv/go-auth/api/internal/repository"
 	"github.com/golang-jwt/jwt/v5"
@@ -14,37 +19,118 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidResetToken        = errors.New("reset link is invalid or has expired")
+	ErrInvalidVerificationToken = errors.New("verification link is invalid or has expired")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrInvalidAccessToken       = errors.New("access token is invalid or expired")
+	ErrInvalidRefreshToken      = errors.New("refresh token is invalid, revoked or expired")
+	ErrIdentityLinkRequired     = errors.New("an account with this email already exists; sign in and link this provider from your account settings")
+	ErrIdentityEmailUnverified  = errors.New("the provider has not verified this email address; verify it there or sign up with a password")
+)
+
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts caps codes tried per challenge, right or wrong; after
+	// that the user must enter their password again.
+	mfaMaxAttempts = 5
+	// refreshReuseGrace is how soon after a rotation the old token may
+	// come back without being treated as stolen.
+	refreshReuseGrace = 5 * time.Second
+)
+
+// ThrottledError reports an action refused because it was retried too soon.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many requests, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
 type AuthService interface {
 	Register(ctx context.Context, email, password string) (models.AuthUser, error)
-	Login(ctx context.Context, email, password string) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
+	Login(ctx context.Context, email, password string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
 	Me(ctx context.Context, userID uuid.UUID) (models.AuthUser, error)
 
+	// Two-factor login. Login returns *MFARequiredError when the account
+	// has MFA; VerifyMFA finishes that sign-in.
+	RequireMFA(ctx context.Context, userID uuid.UUID) error
+	VerifyMFA(ctx context.Context, challengeToken, code string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
+
+	// LoginWithPasskey issues the session for an assertion checked by
+	// PasskeyService.FinishLogin.
+	LoginWithPasskey(ctx context.Context, login PasskeyLogin, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
+
+	// StartSession issues tokens for a user who has passed every factor,
+	// starting a new session for the client.
+	StartSession(ctx context.Context, userID uuid.UUID, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
+	// Refresh exchanges a refresh token for a new pair within the same
+	// session. Each refresh token works once; presenting a rotated one
+	// again ends the session.
+	Refresh(ctx context.Context, refreshJWT string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error)
+
+	// Password reset
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+
+	// Email verification
+	VerifyEmail(ctx context.Context, token string) (models.AuthUser, error)
+	ResendVerification(ctx context.Context, userID uuid.UUID) error
+
 	// Social
-	FindOrCreateOauthUser(ctx context.Context, email, provider, providerId string) (models.AuthUser, error)
+	OAuthLogin(ctx context.Context, id models.ExternalIdentity) (models.AuthUser, error)
 
 	// Tokens
-	GenerateAccessToken(user models.User, roles []string) (string, time.Time, error)
+	GenerateAccessToken(user models.User, roles []string, sessionID uuid.UUID) (string, time.Time, error)
+	ParseAccessToken(accessJWT string) (AccessClaims, error)
+	// CheckRevoked returns ErrTokenRevoked for a token that was logged
+	// out, belongs to a revoked session or predates a password reset or
+	// role change.
+	CheckRevoked(ctx context.Context, claims AccessClaims) error
+	RevokeAccessToken(ctx context.Context, claims AccessClaims) error
+	// AuthenticatePersonalToken accepts a personal access token in place
+	// of an access token.
+	AuthenticatePersonalToken(ctx context.Context, token string) (AccessClaims, error)
 	GenerateFreshToken(user models.User) (string, uuid.UUID, time.Time, error)
 	ValidateRefreshToken(refreshJWT string) (uuid.UUID, uuid.UUID, time.Time, error)
-	SaveRefresh(ctx context.Context, userId uuid.UUID, jti uuid.UUID, exp time.Time) error
 	RevokeRefresh(ctx context.Context, jti uuid.UUID) error
 	RevokeAllForUser(ctx context.Context, userId uuid.UUID) error
-	GetFreshByJTI(ctx context.Context, jti uuid.UUID) (models.RefreshToken, error)
 }
 
 type authService struct {
-	cfg    *config.Config
-	users  repository.UserRepo
-	roles  repository.RoleRepo
-	tokens repository.TokenRepo
+	cfg            *config.Config
+	keys           *jwtkeys.KeySet
+	users          repository.UserRepo
+	roles          repository.RoleRepo
+	tokens         repository.TokenRepo
+	resets         repository.PasswordResetRepo
+	verifies       repository.EmailVerificationRepo
+	identities     repository.IdentityRepo
+	mfa            repository.MFARepo
+	events         repository.SecurityEventRepo
+	revocations    RevocationStore
+	personalTokens repository.PersonalTokenRepo
+	notifier       Notifier
 }
 
-func NewAuthService(cfg *config.Config, users repository.UserRepo, roles repository.RoleRepo, tokens repository.TokenRepo) AuthService {
+// NewAuthService signs access tokens with keys, or with HS256 and
+// cfg.JWTAccessSecret when keys is nil.
+func NewAuthService(cfg *config.Config, keys *jwtkeys.KeySet, users repository.UserRepo, roles repository.RoleRepo, tokens repository.TokenRepo, resets repository.PasswordResetRepo, verifies repository.EmailVerificationRepo, identities repository.IdentityRepo, mfa repository.MFARepo, events repository.SecurityEventRepo, revocations RevocationStore, personalTokens repository.PersonalTokenRepo, notifier Notifier) AuthService {
 	return &authService{
-		cfg:    cfg,
-		users:  users,
-		roles:  roles,
-		tokens: tokens,
+		cfg:            cfg,
+		keys:           keys,
+		users:          users,
+		roles:          roles,
+		tokens:         tokens,
+		resets:         resets,
+		verifies:       verifies,
+		identities:     identities,
+		mfa:            mfa,
+		events:         events,
+		revocations:    revocations,
+		personalTokens: personalTokens,
+		notifier:       notifier,
 	}
 }
 
@@ -60,10 +146,12 @@ func (a *authService) Register(ctx context.Context, email, password string) (mod
 	}
 
 	_ = a.users.AddRole(ctx, u.ID, "user")
+	// A failed send must not fail registration; the user can ask again.
+	_ = a.sendVerification(ctx, u)
 	rs, _ := a.users.GetUserRoles(ctx, u.ID)
 	return models.AuthUser{User: u, Roles: rs}, nil
 }
-func (a *authService) Login(ctx context.Context, email, password string) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+func (a *authService) Login(ctx context.Context, email, password string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
 	u, err := a.users.FindByEmail(ctx, email)
 	if err != nil {
 		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, errors.New("invalid credentials")
@@ -74,8 +162,111 @@ func (a *authService) Login(ctx context.Context, email, password string) (models
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash.String), []byte(password)); err != nil {
 		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, errors.New("invalid credentials")
 	}
+	if err := a.RequireMFA(ctx, u.ID); err != nil {
+		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+	}
+	return a.issueSession(ctx, u, uuid.New(), client)
+}
+
+// LoginWithPasskey treats a user-verified passkey as both factors. Without
+// user verification it only stands in for the password, and accounts with
+// MFA still get a challenge.
+func (a *authService) LoginWithPasskey(ctx context.Context, login PasskeyLogin, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+	u, err := a.users.FindByID(ctx, login.UserID)
+	if err != nil {
+		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+	}
+	if !login.UserVerified {
+		if err := a.RequireMFA(ctx, u.ID); err != nil {
+			return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+		}
+	}
+	return a.issueSession(ctx, u, uuid.New(), client)
+}
+
+func (a *authService) StartSession(ctx context.Context, userID uuid.UUID, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+	u, err := a.users.FindByID(ctx, userID)
+	if err != nil {
+		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+	}
+	return a.issueSession(ctx, u, uuid.New(), client)
+}
+
+func (a *authService) Refresh(ctx context.Context, refreshJWT string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+	fail := func(err error) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+	}
+
+	userID, jti, _, err := a.ValidateRefreshToken(refreshJWT)
+	if err != nil {
+		return fail(ErrInvalidRefreshToken)
+	}
+	rt, err := a.tokens.FindByJTI(ctx, jti)
+	if err != nil || rt.UserID != userID || time.Now().After(rt.ExpiresAt) {
+		return fail(ErrInvalidRefreshToken)
+	}
+	if rt.IsRevoked {
+		a.detectRefreshReuse(ctx, rt, client)
+		return fail(ErrInvalidRefreshToken)
+	}
+	u, err := a.users.FindByID(ctx, userID)
+	if err != nil {
+		return fail(ErrInvalidRefreshToken)
+	}
+
 	roles, _ := a.users.GetUserRoles(ctx, u.ID)
-	access, _, err := a.GenerateAccessToken(u, roles)
+	access, _, err := a.GenerateAccessToken(u, roles, rt.SessionID)
+	if err != nil {
+		return fail(err)
+	}
+	refresh, newJTI, exp, err := a.GenerateFreshToken(u)
+	if err != nil {
+		return fail(err)
+	}
+	next := newRefreshToken(u.ID, newJTI, rt.SessionID, exp, client)
+	next.ParentJTI = &jti
+	if err := a.tokens.Rotate(ctx, jti, next); errors.Is(err, sql.ErrNoRows) {
+		// Another request rotated it first.
+		if rt, err := a.tokens.FindByJTI(ctx, jti); err == nil {
+			a.detectRefreshReuse(ctx, rt, client)
+		}
+		return fail(ErrInvalidRefreshToken)
+	} else if err != nil {
+		return fail(err)
+	}
+	return models.AuthUser{User: u, Roles: roles}, access, refresh, newJTI, exp, nil
+}
+
+// detectRefreshReuse handles a revoked refresh token that was presented
+// again. If it had been rotated, either the client or a thief is holding a
+// stale copy, and there is no telling which; the whole session is revoked
+// so both have to sign in again. Tokens revoked by logout carry no
+// successor and are simply refused.
+func (a *authService) detectRefreshReuse(ctx context.Context, rt models.RefreshToken, client models.ClientInfo) {
+	if rt.ReplacedBy == nil {
+		return
+	}
+	// Two tabs refreshing at once is not an attack.
+	if rt.LastUsedAt != nil && time.Since(*rt.LastUsedAt) < refreshReuseGrace {
+		return
+	}
+	if err := a.tokens.RevokeSession(ctx, rt.UserID, rt.SessionID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("refresh reuse: revoking session %s: %v", rt.SessionID, err)
+	}
+	if err := revokeSessionAccess(ctx, a.revocations, accessTokenTTL(a.cfg), rt.SessionID); err != nil {
+		log.Printf("refresh reuse: revoking access to session %s: %v", rt.SessionID, err)
+	}
+	recordSecurityEvent(ctx, a.events, rt.UserID, models.SecurityEventRefreshReuse, client, models.EventDetails{
+		"session_id": rt.SessionID,
+		"jti":        rt.JTI,
+	})
+}
+
+// issueSession creates the access and refresh tokens for a fully
+// authenticated user.
+func (a *authService) issueSession(ctx context.Context, u models.User, sessionID uuid.UUID, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+	roles, _ := a.users.GetUserRoles(ctx, u.ID)
+	access, _, err := a.GenerateAccessToken(u, roles, sessionID)
 	if err != nil {
 		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
 	}
@@ -84,7 +275,7 @@ func (a *authService) Login(ctx context.Context, email, password string) (models
 	if err != nil {
 		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
 	}
-	if err := a.SaveRefresh(ctx, u.ID, jti, exp); err != nil {
+	if err := a.tokens.Insert(ctx, newRefreshToken(u.ID, jti, sessionID, exp, client)); err != nil {
 		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
 	}
 	return models.AuthUser{User: u, Roles: roles}, access, refresh, jti, exp, nil
@@ -99,25 +290,229 @@ func (a *authService) Me(ctx context.Context, userID uuid.UUID) (models.AuthUser
 	return models.AuthUser{User: u, Roles: roles}, nil
 }
 
-func (a *authService) FindOrCreateOauthUser(ctx context.Context, email, provider, providerId string) (models.AuthUser, error) {
-	u, err := a.users.FindByEmail(ctx, email)
+// RequireMFA returns *MFARequiredError with a fresh challenge when the user
+// has confirmed MFA, and nil otherwise.
+func (a *authService) RequireMFA(ctx context.Context, userID uuid.UUID) error {
+	m, err := a.mfa.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && m.ConfirmedAt == nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newOpaqueToken()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			u, err = a.users.CreateOAuth(ctx, email, provider, providerId)
-			if err != nil {
+		return err
+	}
+	exp := time.Now().Add(mfaChallengeTTL)
+	if err := a.mfa.CreateChallenge(ctx, userID, hashToken(token), exp); err != nil {
+		return err
+	}
+	return &MFARequiredError{Token: token, ExpiresAt: exp}
+}
+
+func (a *authService) VerifyMFA(ctx context.Context, challengeToken, code string, client models.ClientInfo) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+	fail := func(err error) (models.AuthUser, string, string, uuid.UUID, time.Time, error) {
+		return models.AuthUser{}, "", "", uuid.Nil, time.Time{}, err
+	}
+
+	ch, err := a.mfa.ClaimChallengeAttempt(ctx, hashToken(challengeToken), mfaMaxAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fail(ErrInvalidMFAChallenge)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	m, err := a.mfa.Get(ctx, ch.UserID)
+	if err != nil {
+		return fail(err)
+	}
+	if err := verifyMFACode(ctx, a.mfa, m, code); err != nil {
+		return fail(err)
+	}
+	if err := a.mfa.ConsumeChallenge(ctx, ch.ID); errors.Is(err, sql.ErrNoRows) {
+		return fail(ErrInvalidMFAChallenge)
+	} else if err != nil {
+		return fail(err)
+	}
+
+	u, err := a.users.FindByID(ctx, ch.UserID)
+	if err != nil {
+		return fail(err)
+	}
+	return a.issueSession(ctx, u, uuid.New(), client)
+}
+
+// RequestPasswordReset emails a reset link when the address belongs to an
+// account. Unknown addresses succeed silently so callers cannot probe for
+// accounts.
+func (a *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := a.users.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	exp := time.Now().Add(time.Duration(a.cfg.PasswordResetTTLMinutes) * time.Minute)
+	if err := a.resets.Create(ctx, u.ID, hashToken(token), exp); err != nil {
+		return err
+	}
+
+	link := a.cfg.FrontendOrigin + "/reset-password?token=" + url.QueryEscape(token)
+	return a.notifier.SendPasswordReset(ctx, u.Email, link, exp)
+}
+
+// ResetPassword redeems a reset token and signs the user out everywhere.
+// Access tokens are revoked before the token is spent, so a failure there
+// can be retried with the same link.
+func (a *authService) ResetPassword(ctx context.Context, token, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	userID, err := a.resets.FindUser(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeUserAccess(ctx, a.revocations, userID); err != nil {
+		return err
+	}
+	if _, err := a.resets.Redeem(ctx, hashToken(token), string(hash)); errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	} else if err != nil {
+		return err
+	}
+	// A refresh in the meantime could have minted a token after the first
+	// watermark. The reset has gone through, so this one is best effort.
+	if err := revokeUserAccess(ctx, a.revocations, userID); err != nil {
+		log.Printf("password reset for %s: revoking access tokens: %v", userID, err)
+	}
+	return nil
+}
+
+// VerifyEmail redeems a verification token and marks the address as owned.
+func (a *authService) VerifyEmail(ctx context.Context, token string) (models.AuthUser, error) {
+	userID, err := a.verifies.Redeem(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AuthUser{}, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return models.AuthUser{}, err
+	}
+	return a.Me(ctx, userID)
+}
+
+// ResendVerification sends a fresh verification link, at most once per
+// EmailVerificationResendSeconds.
+func (a *authService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	u, err := a.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	last, err := a.verifies.LastSentAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	wait := time.Duration(a.cfg.EmailVerificationResendSeconds) * time.Second
+	if next := last.Add(wait); time.Now().Before(next) {
+		return &ThrottledError{RetryAfter: time.Until(next)}
+	}
+	return a.sendVerification(ctx, u)
+}
+
+func (a *authService) sendVerification(ctx context.Context, u models.User) error {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	exp := time.Now().Add(time.Duration(a.cfg.EmailVerificationTTLHours) * time.Hour)
+	if err := a.verifies.Create(ctx, u.ID, hashToken(token), exp); err != nil {
+		return err
+	}
+
+	link := a.cfg.FrontendOrigin + "/verify-email?token=" + url.QueryEscape(token)
+	return a.notifier.SendEmailVerification(ctx, u.Email, link, exp)
+}
+
+// OAuthLogin signs in the user an external identity belongs to, creating a
+// new account when the email is unknown and the provider verified it. An
+// identity is never attached to an
+// existing account silently: that needs an explicit link, unless the
+// auto-link policy allows it because both sides have verified the address.
+func (a *authService) OAuthLogin(ctx context.Context, id models.ExternalIdentity) (models.AuthUser, error) {
+	existing, err := a.identities.FindBySubject(ctx, id.Provider, id.Subject)
+	switch {
+	case err == nil:
+		if err := a.identities.TouchLogin(ctx, existing.ID); err != nil {
+			return models.AuthUser{}, err
+		}
+		u, err := a.users.FindByID(ctx, existing.UserID)
+		if err != nil {
+			return models.AuthUser{}, err
+		}
+		if id.EmailVerified && u.EmailVerifiedAt == nil && strings.EqualFold(u.Email, id.Email) {
+			if u, err = a.users.MarkEmailVerified(ctx, u.ID); err != nil {
 				return models.AuthUser{}, err
 			}
-		} else {
+		}
+		return a.authUser(ctx, u)
+	case !errors.Is(err, sql.ErrNoRows):
+		return models.AuthUser{}, err
+	}
+
+	u, err := a.users.FindByEmail(ctx, strings.ToLower(id.Email))
+	if errors.Is(err, sql.ErrNoRows) {
+		// An unproven address would let someone hold an account before its
+		// owner signs up.
+		if !id.EmailVerified {
+			return models.AuthUser{}, ErrIdentityEmailUnverified
+		}
+		if u, err = a.identities.CreateUser(ctx, id); err != nil {
 			return models.AuthUser{}, err
 		}
+		_ = a.users.AddRole(ctx, u.ID, "user")
+		return a.authUser(ctx, u)
+	}
+	if err != nil {
+		return models.AuthUser{}, err
+	}
+
+	if a.cfg.OAuthAutoLink != config.AutoLinkVerified || !id.EmailVerified || u.EmailVerifiedAt == nil {
+		return models.AuthUser{}, ErrIdentityLinkRequired
 	}
+	if _, err := a.identities.Link(ctx, u.ID, id); err != nil {
+		return models.AuthUser{}, err
+	}
+	return a.authUser(ctx, u)
+}
+
+func (a *authService) authUser(ctx context.Context, u models.User) (models.AuthUser, error) {
 	roles, _ := a.users.GetUserRoles(ctx, u.ID)
 	return models.AuthUser{User: u, Roles: roles}, nil
 }
 
 type accessClaims struct {
-	UserId string   `json:"uid"`
-	Roles  []string `json:"roles"`
+	UserId    string   `json:"uid"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"sid,omitempty"`
+	// IssuedAtMs is iat in milliseconds, fine enough to tell a token from
+	// a revocation made in the same second.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -126,24 +521,152 @@ type refreshClaims struct {
 	jwt.RegisteredClaims
 }
 
-func (a *authService) GenerateAccessToken(user models.User, roles []string) (string, time.Time, error) {
+func (a *authService) GenerateAccessToken(user models.User, roles []string, sessionID uuid.UUID) (string, time.Time, error) {
 	now := time.Now()
 	exp := now.Add(time.Duration(a.cfg.JWTAccessTTLMinutes) * time.Minute)
 
 	claims := accessClaims{
-		UserId: user.ID.String(),
-		Roles:  roles,
+		UserId:     user.ID.String(),
+		Roles:      roles,
+		SessionID:  sessionID.String(),
+		IssuedAtMs: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    a.cfg.JWTIssuer,
 			Subject:   user.ID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	s, err := token.SignedString([]byte(a.cfg.JWTAccessSecret))
+	if a.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		s, err := token.SignedString([]byte(a.cfg.JWTAccessSecret))
+		return s, exp, err
+	}
+	key := a.keys.Active()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.ID
+	s, err := token.SignedString(key.Private)
 	return s, exp, err
 }
+
+// AccessClaims is what a verified access token says about its bearer.
+type AccessClaims struct {
+	UserID uuid.UUID
+	Roles  []string
+	// SessionID is uuid.Nil for tokens issued before sessions were
+	// tracked, and TokenID empty for those issued before revocation.
+	SessionID uuid.UUID
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// PersonalTokenID is set when a personal access token was used. Such
+	// requests are limited to Scopes and carry no roles; Scopes is nil for
+	// browser sessions, which can do anything the user can.
+	PersonalTokenID uuid.UUID
+	Scopes          []string
+}
+
+// ParseAccessToken verifies an access token. With a keyset only its
+// algorithms are accepted, and the key named by kid decides which one, so
+// an HS256 token signed with a public key cannot pass.
+func (a *authService) ParseAccessToken(accessJWT string) (AccessClaims, error) {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if a.cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.cfg.JWTIssuer))
+	}
+
+	var keyFunc jwt.Keyfunc
+	if a.keys == nil {
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		keyFunc = func(t *jwt.Token) (any, error) {
+			return []byte(a.cfg.JWTAccessSecret), nil
+		}
+	} else {
+		opts = append(opts, jwt.WithValidMethods(jwtkeys.Algorithms))
+		keyFunc = func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			k, ok := a.keys.Lookup(kid)
+			if !ok || t.Method.Alg() != k.Alg {
+				return nil, ErrInvalidAccessToken
+			}
+			return k.Public, nil
+		}
+	}
+
+	var c accessClaims
+	if _, err := jwt.ParseWithClaims(accessJWT, &c, keyFunc, opts...); err != nil {
+		return AccessClaims{}, ErrInvalidAccessToken
+	}
+	uid, err := uuid.Parse(c.UserId)
+	if err != nil || c.IssuedAt == nil {
+		return AccessClaims{}, ErrInvalidAccessToken
+	}
+	sid, _ := uuid.Parse(c.SessionID)
+	issuedAt := c.IssuedAt.Time
+	if c.IssuedAtMs != 0 {
+		issuedAt = time.UnixMilli(c.IssuedAtMs)
+	}
+	return AccessClaims{
+		UserID:    uid,
+		Roles:     c.Roles,
+		SessionID: sid,
+		TokenID:   c.ID,
+		IssuedAt:  issuedAt,
+		ExpiresAt: c.ExpiresAt.Time,
+	}, nil
+}
+
+func (a *authService) CheckRevoked(ctx context.Context, claims AccessClaims) error {
+	var ids []string
+	if claims.TokenID != "" {
+		ids = append(ids, claims.TokenID)
+	}
+	if claims.SessionID != uuid.Nil {
+		ids = append(ids, claims.SessionID.String())
+	}
+	revoked, err := a.revocations.Revoked(ctx, claims.UserID, claims.IssuedAt, ids...)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+func (a *authService) AuthenticatePersonalToken(ctx context.Context, token string) (AccessClaims, error) {
+	t, err := a.personalTokens.FindActive(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return AccessClaims{}, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	if err := a.personalTokens.TouchLastUsed(ctx, t.ID, personalTokenTouchInterval); err != nil {
+		log.Printf("personal token %s: recording use: %v", t.ID, err)
+	}
+	scopes := []string(t.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return AccessClaims{
+		UserID:          t.UserID,
+		Roles:           []string{},
+		ExpiresAt:       t.ExpiresAt,
+		PersonalTokenID: t.ID,
+		Scopes:          scopes,
+	}, nil
+}
+
+func (a *authService) RevokeAccessToken(ctx context.Context, claims AccessClaims) error {
+	if claims.TokenID == "" {
+		return nil
+	}
+	return a.revocations.Revoke(ctx, claims.TokenID, claims.ExpiresAt)
+}
 func (a *authService) GenerateFreshToken(user models.User) (string, uuid.UUID, time.Time, error) {
 	now := time.Now()
 	exp := now.Add(time.Duration(a.cfg.JWTRefreshTTLHrs) * time.Hour)
@@ -192,14 +715,16 @@ func (a *authService) ValidateRefreshToken(refreshJWT string) (uuid.UUID, uuid.U
 	return uid, jti, rc.ExpiresAt.Time, nil
 }
 
-func (a *authService) SaveRefresh(ctx context.Context, userId uuid.UUID, jti uuid.UUID, exp time.Time) error {
-	return a.tokens.Insert(ctx, models.RefreshToken{
+func newRefreshToken(userID, jti, sessionID uuid.UUID, exp time.Time, client models.ClientInfo) models.RefreshToken {
+	return models.RefreshToken{
 		ID:        uuid.New(),
-		UserID:    userId,
+		UserID:    userID,
 		JTI:       jti,
-		IsRevoked: false,
+		SessionID: sessionID,
+		UserAgent: boundedUserAgent(client.UserAgent),
+		IP:        client.IP,
 		ExpiresAt: exp,
-	})
+	}
 }
 
 func (a *authService) RevokeRefresh(ctx context.Context, jti uuid.UUID) error {
@@ -208,6 +733,3 @@ func (a *authService) RevokeRefresh(ctx context.Context, jti uuid.UUID) error {
 func (a *authService) RevokeAllForUser(ctx context.Context, userId uuid.UUID) error {
 	return a.tokens.RevokeAllForUser(ctx, userId)
 }
-func (a *authService) GetFreshByJTI(ctx context.Context, jti uuid.UUID) (models.RefreshToken, error) {
-	return a.tokens.FindByJTI(ctx, jti)
-}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrInvalidCategory  = errors.New("category name is required")
+	ErrCategoryExists   = errors.New("a sibling category with this name already exists")
+	// ErrCategoryCycle is detected by the repository, inside the move
+	// transaction.
+	ErrCategoryCycle         = repository.ErrCategoryCycle
+	ErrInvalidCategoryOrder  = errors.New("ids must list every sibling exactly once")
+	ErrInvalidDeleteStrategy = errors.New("strategy must be reparent or cascade")
+)
+
+const (
+	maxCategoryNameLength              = 100
+	uniqueViolation       pq.ErrorCode = "23505"
+)
+
+type CategoryService interface {
+	Tree(ctx context.Context, userID uuid.UUID) ([]*models.Category, error)
+	Create(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, name string) (models.Category, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Category, error)
+	Move(ctx context.Context, userID, id uuid.UUID, parentID uuid.NullUUID, position int) (models.Category, error)
+	Reorder(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, ids []uuid.UUID) error
+	Delete(ctx context.Context, userID, id uuid.UUID, strategy string) error
+}
+
+type categoryService struct {
+	categories repository.CategoryRepo
+}
+
+func NewCategoryService(categories repository.CategoryRepo) CategoryService {
+	return &categoryService{categories: categories}
+}
+
+// Tree returns the user's categories as a forest ordered by position.
+func (s *categoryService) Tree(ctx context.Context, userID uuid.UUID) ([]*models.Category, error) {
+	flat, err := s.categories.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*models.Category, len(flat))
+	for i := range flat {
+		flat[i].Children = []*models.Category{}
+		nodes[flat[i].ID] = &flat[i]
+	}
+
+	roots := []*models.Category{}
+	for i := range flat {
+		c := &flat[i]
+		if parent, ok := nodes[c.ParentID.UUID]; c.ParentID.Valid && ok {
+			parent.Children = append(parent.Children, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	return roots, nil
+}
+
+func (s *categoryService) Create(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, name string) (models.Category, error) {
+	name, err := normalizeCategoryName(name)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	c, err := s.categories.Create(ctx, userID, parentID, name)
+	return c, categoryError(err)
+}
+
+func (s *categoryService) Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Category, error) {
+	name, err := normalizeCategoryName(name)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	c, err := s.categories.Rename(ctx, userID, id, name)
+	return c, categoryError(err)
+}
+
+func (s *categoryService) Move(ctx context.Context, userID, id uuid.UUID, parentID uuid.NullUUID, position int) (models.Category, error) {
+	if parentID.Valid && parentID.UUID == id {
+		return models.Category{}, ErrCategoryCycle
+	}
+
+	c, err := s.categories.Move(ctx, userID, id, parentID, position)
+	return c, categoryError(err)
+}
+
+func (s *categoryService) Reorder(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, ids []uuid.UUID) error {
+	err := s.categories.Reorder(ctx, userID, parentID, ids)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidCategoryOrder
+	}
+	return err
+}
+
+func (s *categoryService) Delete(ctx context.Context, userID, id uuid.UUID, strategy string) error {
+	if strategy != models.CategoryDeleteReparent && strategy != models.CategoryDeleteCascade {
+		return ErrInvalidDeleteStrategy
+	}
+	return categoryError(s.categories.Delete(ctx, userID, id, strategy))
+}
+
+func normalizeCategoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxCategoryNameLength {
+		return "", ErrInvalidCategory
+	}
+	return name, nil
+}
+
+func categoryError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrCategoryNotFound
+	case isUniqueViolation(err):
+		return ErrCategoryExists
+	default:
+		return err
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
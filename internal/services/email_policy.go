@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrEmailNotVerified = errors.New("verify your email address first")
+
+// EmailPolicy gates actions that expose a user's content to others, such as
+// publishing public prompts and creating share links.
+type EmailPolicy interface {
+	// RequireVerified returns ErrEmailNotVerified when the policy is on and
+	// the user has not confirmed their address.
+	RequireVerified(ctx context.Context, userID uuid.UUID) error
+}
+
+type emailPolicy struct {
+	cfg   *config.Config
+	users repository.UserRepo
+}
+
+func NewEmailPolicy(cfg *config.Config, users repository.UserRepo) EmailPolicy {
+	return &emailPolicy{cfg: cfg, users: users}
+}
+
+func (p *emailPolicy) RequireVerified(ctx context.Context, userID uuid.UUID) error {
+	if !p.cfg.RequireVerifiedEmail {
+		return nil
+	}
+	u, err := p.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidExportFormat = errors.New("export format must be json, markdown or zip")
+
+const (
+	ExportJSON     = "json"
+	ExportMarkdown = "markdown"
+	ExportZip      = "zip"
+)
+
+const (
+	exportLibraryFile = "library.json"
+	exportPromptDir   = "prompts"
+	maxSlugLength     = 60
+)
+
+type ExportService interface {
+	// Export writes the user's library to w as it is read from the
+	// database; nothing is buffered beyond a single prompt.
+	Export(ctx context.Context, userID uuid.UUID, format string, w io.Writer) error
+}
+
+type exportService struct {
+	exports repository.ExportRepo
+}
+
+func NewExportService(exports repository.ExportRepo) ExportService {
+	return &exportService{exports: exports}
+}
+
+func (s *exportService) Export(ctx context.Context, userID uuid.UUID, format string, w io.Writer) error {
+	if format != ExportJSON && format != ExportMarkdown && format != ExportZip {
+		return ErrInvalidExportFormat
+	}
+
+	return s.exports.Snapshot(ctx, userID, func(snap repository.ExportSnapshot) error {
+		lib, err := snap.Library(ctx)
+		if err != nil {
+			return err
+		}
+		if format == ExportJSON {
+			return writeLibraryJSON(ctx, w, snap, lib)
+		}
+
+		zw := zip.NewWriter(w)
+		if format == ExportZip {
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: exportLibraryFile, Method: zip.Deflate, Modified: time.Now()})
+			if err != nil {
+				return err
+			}
+			if err := writeLibraryJSON(ctx, f, snap, lib); err != nil {
+				return err
+			}
+		}
+		if err := writeMarkdownFiles(ctx, zw, snap, lib); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// writeLibraryJSON streams a models.LibraryDocument. The header is encoded
+// on its own and the prompts array is spliced into it, so prompts can be
+// written as they are read.
+func writeLibraryJSON(ctx context.Context, w io.Writer, snap repository.ExportSnapshot, lib models.ExportLibrary) error {
+	header, err := json.Marshal(models.LibraryHeader{
+		Format:        models.LibraryFormat,
+		Version:       models.LibraryVersion,
+		ExportedAt:    time.Now().UTC(),
+		ExportLibrary: lib,
+	})
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header[:len(header)-1])
+	bw.WriteString(`,"prompts":[`)
+	first := true
+	err = snap.EachPrompt(ctx, func(p models.ExportPrompt) error {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		_, err = bw.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+type promptFrontMatter struct {
+	ID          string                   `yaml:"id"`
+	ExternalID  string                   `yaml:"external_id,omitempty"`
+	Title       string                   `yaml:"title"`
+	Description string                   `yaml:"description,omitempty"`
+	Model       string                   `yaml:"model,omitempty"`
+	Category    string                   `yaml:"category,omitempty"`
+	Tags        []string                 `yaml:"tags,omitempty,flow"`
+	Visibility  string                   `yaml:"visibility"`
+	Favorite    bool                     `yaml:"favorite,omitempty"`
+	Version     int                      `yaml:"version"`
+	Variables   models.TemplateVariables `yaml:"variables,omitempty"`
+	CreatedAt   time.Time                `yaml:"created_at"`
+	UpdatedAt   time.Time                `yaml:"updated_at"`
+}
+
+// writeMarkdownFiles adds one Markdown file per prompt, filed in
+// directories mirroring its category.
+func writeMarkdownFiles(ctx context.Context, zw *zip.Writer, snap repository.ExportSnapshot, lib models.ExportLibrary) error {
+	categories := categoryPaths(lib.Categories)
+
+	return snap.EachPrompt(ctx, func(p models.ExportPrompt) error {
+		category := categories[p.CategoryID.UUID]
+		if !p.CategoryID.Valid {
+			category = nil
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("---\n")
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(promptFrontMatter{
+			ID:          p.ID.String(),
+			ExternalID:  p.ExternalID,
+			Title:       p.Title,
+			Description: p.Description,
+			Model:       p.Model,
+			Category:    strings.Join(category, "/"),
+			Tags:        p.Tags,
+			Visibility:  p.Visibility,
+			Favorite:    p.Favorited,
+			Version:     p.CurrentVersion,
+			Variables:   p.Variables,
+			CreatedAt:   p.CreatedAt.UTC(),
+			UpdatedAt:   p.UpdatedAt.UTC(),
+		}); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		buf.WriteString("---\n\n")
+		buf.WriteString(strings.TrimRight(p.Body, "\n"))
+		buf.WriteString("\n")
+
+		dir := []string{exportPromptDir}
+		for _, name := range category {
+			dir = append(dir, slugify(name, "category"))
+		}
+		name := path.Join(append(dir, slugify(p.Title, "prompt")+"-"+p.ID.String()[:8]+".md")...)
+
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: p.UpdatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(buf.Bytes())
+		return err
+	})
+}
+
+// categoryPaths maps each category to its names from the root down. A
+// category whose parent is missing, or that sits in a cycle, is treated as a
+// root.
+func categoryPaths(categories []models.ExportCategory) map[uuid.UUID][]string {
+	byID := make(map[uuid.UUID]models.ExportCategory, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	paths := make(map[uuid.UUID][]string, len(categories))
+	visiting := map[uuid.UUID]bool{}
+	var resolve func(c models.ExportCategory) []string
+	resolve = func(c models.ExportCategory) []string {
+		if p, ok := paths[c.ID]; ok {
+			return p
+		}
+		visiting[c.ID] = true
+		var parent []string
+		if pc, ok := byID[c.ParentID.UUID]; c.ParentID.Valid && ok && !visiting[pc.ID] {
+			parent = resolve(pc)
+		}
+		visiting[c.ID] = false
+		paths[c.ID] = append(append([]string{}, parent...), c.Name)
+		return paths[c.ID]
+	}
+	for _, c := range categories {
+		resolve(c)
+	}
+	return paths
+}
+
+// slugify reduces s to lowercase letters, digits and single dashes for use
+// as a file name, falling back to fallback when nothing is left.
+func slugify(s, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= maxSlugLength {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return fallback
+	}
+	return b.String()
+}
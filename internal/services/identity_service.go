@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrIdentityNotFound   = errors.New("identity not found")
+	ErrIdentityInUse      = errors.New("this identity is already linked to another account")
+	ErrLastLoginMethod    = errors.New("cannot remove the last way to sign in; set a password, link a provider or add a passkey first")
+	ErrReauthRequired     = errors.New("current password is incorrect")
+	ErrInvalidLinkRequest = errors.New("link request is invalid or has expired")
+)
+
+// linkIntentTTL covers the round trip to the provider.
+const linkIntentTTL = 10 * time.Minute
+
+type IdentityService interface {
+	List(ctx context.Context, userID uuid.UUID) (models.LoginMethods, error)
+	// BeginLink re-authenticates the user and returns a short-lived,
+	// single-use intent that the OAuth flow carries to CompleteLink.
+	// Accounts with a password must supply it.
+	BeginLink(ctx context.Context, userID uuid.UUID, provider, password string) (string, error)
+	CompleteLink(ctx context.Context, intent string, id models.ExternalIdentity) (models.UserIdentity, error)
+	// LinkIntentProvider validates an intent without consuming it.
+	LinkIntentProvider(ctx context.Context, intent string) (string, error)
+	Unlink(ctx context.Context, userID, identityID uuid.UUID) error
+}
+
+type identityService struct {
+	cfg        *config.Config
+	users      repository.UserRepo
+	identities repository.IdentityRepo
+}
+
+func NewIdentityService(cfg *config.Config, users repository.UserRepo, identities repository.IdentityRepo) IdentityService {
+	return &identityService{cfg: cfg, users: users, identities: identities}
+}
+
+func (s *identityService) List(ctx context.Context, userID uuid.UUID) (models.LoginMethods, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return models.LoginMethods{}, err
+	}
+	identities, err := s.identities.ListForUser(ctx, userID)
+	if err != nil {
+		return models.LoginMethods{}, err
+	}
+	return models.LoginMethods{HasPassword: u.PasswordHash.Valid, Identities: identities}, nil
+}
+
+func (s *identityService) BeginLink(ctx context.Context, userID uuid.UUID, provider, password string) (string, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if u.PasswordHash.Valid {
+		if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash.String), []byte(password)); err != nil {
+			return "", ErrReauthRequired
+		}
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.identities.CreateLinkIntent(ctx, u.ID, provider, hashToken(token), time.Now().Add(linkIntentTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *identityService) LinkIntentProvider(ctx context.Context, intent string) (string, error) {
+	li, err := s.identities.FindLinkIntent(ctx, hashToken(intent))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidLinkRequest
+	}
+	if err != nil {
+		return "", err
+	}
+	return li.Provider, nil
+}
+
+func (s *identityService) CompleteLink(ctx context.Context, intent string, id models.ExternalIdentity) (models.UserIdentity, error) {
+	li, err := s.identities.RedeemLinkIntent(ctx, hashToken(intent))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserIdentity{}, ErrInvalidLinkRequest
+	}
+	if err != nil {
+		return models.UserIdentity{}, err
+	}
+	if li.Provider != id.Provider {
+		return models.UserIdentity{}, ErrInvalidLinkRequest
+	}
+
+	i, err := s.identities.Link(ctx, li.UserID, id)
+	if errors.Is(err, repository.ErrIdentityInUse) {
+		return models.UserIdentity{}, ErrIdentityInUse
+	}
+	return i, err
+}
+
+func (s *identityService) Unlink(ctx context.Context, userID, identityID uuid.UUID) error {
+	err := s.identities.Unlink(ctx, userID, identityID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrIdentityNotFound
+	case errors.Is(err, repository.ErrLastLoginMethod):
+		return ErrLastLoginMethod
+	}
+	return err
+}
@@ -0,0 +1,600 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/importers"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/congdv/go-auth/api/internal/templating"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidImportStrategy = errors.New("strategy must be skip, overwrite, rename or new_version")
+	ErrUnknownImportSource   = errors.New("unknown import source")
+)
+
+const (
+	// maxImportEntryBytes bounds each file unpacked from an archive.
+	maxImportEntryBytes = 16 << 20
+	importChangeNote    = "Imported"
+	importRenameSuffix  = "imported"
+)
+
+type ImportService interface {
+	// Import applies files in a single transaction. A dry run performs
+	// every write and then rolls back, so the report is exact.
+	Import(ctx context.Context, userID uuid.UUID, files []models.ImportFile, opts models.ImportOptions) (models.ImportReport, error)
+}
+
+type importService struct {
+	imports repository.ImportRepo
+	sources *importers.Registry
+	policy  EmailPolicy
+}
+
+func NewImportService(imports repository.ImportRepo, sources *importers.Registry, policy EmailPolicy) ImportService {
+	return &importService{imports: imports, sources: sources, policy: policy}
+}
+
+// importItem is one prompt read from an import file, already validated.
+type importItem struct {
+	source   string
+	key      string
+	category []string
+	favorite bool
+	prompt   models.Prompt
+	tags     []string
+	versions []models.PromptVersion
+}
+
+func (s *importService) Import(ctx context.Context, userID uuid.UUID, files []models.ImportFile, opts models.ImportOptions) (models.ImportReport, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = models.ImportSkip
+	}
+	switch opts.Strategy {
+	case models.ImportSkip, models.ImportOverwrite, models.ImportRename, models.ImportNewVersion:
+	default:
+		return models.ImportReport{}, ErrInvalidImportStrategy
+	}
+
+	var (
+		items    []importItem
+		failures []models.ImportIssue
+		err      error
+	)
+	if opts.Source == "" || opts.Source == models.ImportSourceNative {
+		items, err = parseImportFiles(files)
+	} else {
+		imp, ok := s.sources.Get(opts.Source)
+		if !ok {
+			return models.ImportReport{}, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownImportSource,
+				opts.Source, strings.Join(append([]string{models.ImportSourceNative}, s.sources.Names()...), ", "))
+		}
+		items, failures, err = parseThirdPartyFiles(imp, files)
+	}
+	if err != nil {
+		return models.ImportReport{}, err
+	}
+	// Unverified users may import but not publish; public prompts land as
+	// private instead of failing the whole file.
+	if err := s.policy.RequireVerified(ctx, userID); errors.Is(err, ErrEmailNotVerified) {
+		for i := range items {
+			if items[i].prompt.Visibility == models.VisibilityPublic {
+				items[i].prompt.Visibility = models.VisibilityPrivate
+			}
+		}
+	} else if err != nil {
+		return models.ImportReport{}, err
+	}
+
+	tx, err := s.imports.Begin(ctx, userID)
+	if err != nil {
+		return models.ImportReport{}, err
+	}
+	defer tx.Rollback()
+
+	report := models.ImportReport{
+		DryRun:   opts.DryRun,
+		Strategy: opts.Strategy,
+		Summary:  map[string]int{},
+		Items:    make([]models.ImportItemResult, 0, len(items)),
+		Failures: failures,
+	}
+	if report.Failures == nil {
+		report.Failures = []models.ImportIssue{}
+	}
+	if len(failures) > 0 {
+		report.Summary[models.ImportActionFailed] = len(failures)
+	}
+	for _, item := range items {
+		res, err := applyImportItem(ctx, tx, item, opts.Strategy)
+		if err != nil {
+			return models.ImportReport{}, fmt.Errorf("%s: %w", item.source, err)
+		}
+		report.Summary[res.Action]++
+		report.Items = append(report.Items, res)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	return report, tx.Commit()
+}
+
+func applyImportItem(ctx context.Context, tx repository.ImportTx, item importItem, strategy string) (models.ImportItemResult, error) {
+	res := models.ImportItemResult{Source: item.source, ExternalID: item.key, Title: item.prompt.Title}
+
+	var existing models.Prompt
+	found := false
+	if item.key != "" {
+		p, err := tx.FindByKey(ctx, item.key)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return res, err
+		}
+		existing, found = p, err == nil
+	}
+	if found && strategy == models.ImportSkip {
+		res.Action = models.ImportActionSkipped
+		res.PromptID = existing.ID
+		return res, nil
+	}
+
+	p := item.prompt
+	categoryID, err := tx.EnsureCategory(ctx, item.category)
+	if err != nil {
+		return res, err
+	}
+	p.CategoryID = categoryID
+
+	switch {
+	case !found:
+		res.Action = models.ImportActionCreated
+		if item.key != "" {
+			p.ExternalID = sql.NullString{String: item.key, Valid: true}
+		}
+		p, err = tx.Create(ctx, p, item.versions)
+	case strategy == models.ImportOverwrite:
+		res.Action = models.ImportActionOverwritten
+		p.ID = existing.ID
+		p, err = tx.Overwrite(ctx, p, item.versions)
+	case strategy == models.ImportNewVersion:
+		res.Action = models.ImportActionVersioned
+		if sameContent(existing, p) {
+			res.Action = models.ImportActionSkipped
+			res.PromptID = existing.ID
+			return res, nil
+		}
+		p.ID = existing.ID
+		p, err = tx.AppendVersion(ctx, p, importChangeNote)
+	case strategy == models.ImportRename:
+		res.Action = models.ImportActionRenamed
+		if p.Title, err = uniqueImportTitle(ctx, tx, p.Title); err != nil {
+			return res, err
+		}
+		res.Title = p.Title
+		p, err = tx.Create(ctx, p, renameVersions(item.versions, p.Title))
+	}
+	if err != nil {
+		return res, err
+	}
+	res.PromptID = p.ID
+
+	if err := tx.SetTags(ctx, p.ID, item.tags); err != nil {
+		return res, err
+	}
+	if item.favorite {
+		if err := tx.SetFavorite(ctx, p.ID); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func sameContent(a, b models.Prompt) bool {
+	return a.Title == b.Title && a.Description == b.Description && a.Body == b.Body &&
+		a.Model == b.Model && reflect.DeepEqual(a.Variables, b.Variables)
+}
+
+// uniqueImportTitle suffixes title until no prompt of the user has it.
+func uniqueImportTitle(ctx context.Context, tx repository.ImportTx, title string) (string, error) {
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%s)", title, importRenameSuffix)
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%s %d)", title, importRenameSuffix, n)
+		}
+		exists, err := tx.TitleExists(ctx, candidate)
+		if err != nil || !exists {
+			return candidate, err
+		}
+	}
+}
+
+// renameVersions keeps a renamed copy's head revision in step with its new
+// title.
+func renameVersions(versions []models.PromptVersion, title string) []models.PromptVersion {
+	out := append([]models.PromptVersion{}, versions...)
+	out[len(out)-1].Title = title
+	return out
+}
+
+func parseImportFiles(files []models.ImportFile) ([]importItem, error) {
+	var items []importItem
+	var issues []models.ImportIssue
+	for _, f := range files {
+		parsed, fileIssues := parseImportFile(f)
+		items = append(items, parsed...)
+		issues = append(issues, fileIssues...)
+	}
+	if len(items) == 0 && len(issues) == 0 {
+		issues = append(issues, models.ImportIssue{Source: "request", Message: "no prompts found"})
+	}
+	if len(issues) > 0 {
+		return nil, &models.ImportError{Issues: issues}
+	}
+	return items, nil
+}
+
+// parseThirdPartyFiles runs imp over each file. Unreadable files reject the
+// import; unreadable or invalid rows are only reported.
+func parseThirdPartyFiles(imp importers.Importer, files []models.ImportFile) ([]importItem, []models.ImportIssue, error) {
+	var items []importItem
+	var failures, issues []models.ImportIssue
+	for _, f := range files {
+		res, err := imp.Parse(f.Name, f.Data)
+		if err != nil {
+			issues = append(issues, models.ImportIssue{Source: f.Name, Message: err.Error()})
+			continue
+		}
+		for _, fail := range res.Failures {
+			failures = append(failures, models.ImportIssue{Source: fail.Source, Message: fail.Message})
+		}
+		for _, rec := range res.Records {
+			item, err := newImportItem(rec.Source, rec.ExternalID, rec.Category, false, models.Prompt{
+				Title:       rec.Title,
+				Description: rec.Description,
+				Body:        rec.Body,
+				Model:       rec.Model,
+				CreatedAt:   rec.CreatedAt,
+			}, rec.Tags, nil)
+			if err != nil {
+				failures = append(failures, models.ImportIssue{Source: rec.Source, Message: err.Error()})
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+	if len(issues) > 0 {
+		return nil, nil, &models.ImportError{Issues: issues}
+	}
+	if len(items) == 0 && len(failures) == 0 {
+		return nil, nil, &models.ImportError{Issues: []models.ImportIssue{{Source: "request", Message: "no prompts found"}}}
+	}
+	return items, failures, nil
+}
+
+func parseImportFile(f models.ImportFile) ([]importItem, []models.ImportIssue) {
+	switch importKind(f) {
+	case ExportJSON:
+		return parseLibraryDocument(f)
+	case ExportMarkdown:
+		item, err := parseMarkdownPrompt(f)
+		if err != nil {
+			return nil, []models.ImportIssue{{Source: f.Name, Message: err.Error()}}
+		}
+		return []importItem{item}, nil
+	case ExportZip:
+		return parseImportArchive(f)
+	default:
+		return nil, []models.ImportIssue{{Source: f.Name, Message: "unsupported file type"}}
+	}
+}
+
+// importKind picks a parser from the file extension, falling back to the
+// content for unnamed uploads.
+func importKind(f models.ImportFile) string {
+	switch strings.ToLower(path.Ext(f.Name)) {
+	case ".json":
+		return ExportJSON
+	case ".md", ".markdown":
+		return ExportMarkdown
+	case ".zip":
+		return ExportZip
+	}
+	trimmed := bytes.TrimSpace(f.Data)
+	switch {
+	case bytes.HasPrefix(f.Data, []byte("PK\x03\x04")):
+		return ExportZip
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return ExportJSON
+	case bytes.HasPrefix(trimmed, []byte("---")):
+		return ExportMarkdown
+	}
+	return ""
+}
+
+// parseImportArchive reads a ZIP. When it holds a library document (as the
+// zip export does) only that is used, since the Markdown files duplicate it.
+func parseImportArchive(f models.ImportFile) ([]importItem, []models.ImportIssue) {
+	zr, err := zip.NewReader(bytes.NewReader(f.Data), int64(len(f.Data)))
+	if err != nil {
+		return nil, []models.ImportIssue{{Source: f.Name, Message: "invalid zip archive"}}
+	}
+
+	var libraries, markdown []*zip.File
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || strings.HasPrefix(zf.Name, "__MACOSX/") || strings.HasPrefix(path.Base(zf.Name), ".") {
+			continue
+		}
+		switch strings.ToLower(path.Ext(zf.Name)) {
+		case ".json":
+			libraries = append(libraries, zf)
+		case ".md", ".markdown":
+			markdown = append(markdown, zf)
+		}
+	}
+	entries := markdown
+	if len(libraries) > 0 {
+		entries = libraries
+	}
+
+	var items []importItem
+	var issues []models.ImportIssue
+	for _, zf := range entries {
+		source := f.Name + "/" + zf.Name
+		data, err := readZipEntry(zf)
+		if err != nil {
+			issues = append(issues, models.ImportIssue{Source: source, Message: err.Error()})
+			continue
+		}
+		parsed, entryIssues := parseImportFile(models.ImportFile{Name: source, Data: data})
+		items = append(items, parsed...)
+		issues = append(issues, entryIssues...)
+	}
+	return items, issues
+}
+
+func readZipEntry(zf *zip.File) ([]byte, error) {
+	if zf.UncompressedSize64 > maxImportEntryBytes {
+		return nil, errors.New("file is too large")
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The header size is not trusted; the limit guards against zip bombs.
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportEntryBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportEntryBytes {
+		return nil, errors.New("file is too large")
+	}
+	return data, nil
+}
+
+func parseLibraryDocument(f models.ImportFile) ([]importItem, []models.ImportIssue) {
+	var doc models.LibraryDocument
+	if err := json.Unmarshal(f.Data, &doc); err != nil {
+		return nil, []models.ImportIssue{{Source: f.Name, Message: "invalid JSON: " + err.Error()}}
+	}
+	if doc.Format != models.LibraryFormat {
+		return nil, []models.ImportIssue{{Source: f.Name, Message: "not a library export"}}
+	}
+	if doc.Version < 1 || doc.Version > models.LibraryVersion {
+		return nil, []models.ImportIssue{{Source: f.Name, Message: fmt.Sprintf("unsupported library version %d", doc.Version)}}
+	}
+
+	categories := categoryPaths(doc.Categories)
+	favorites := make(map[uuid.UUID]bool, len(doc.Favorites))
+	for _, id := range doc.Favorites {
+		favorites[id] = true
+	}
+
+	var items []importItem
+	var issues []models.ImportIssue
+	for i, p := range doc.Prompts {
+		source := fmt.Sprintf("%s#prompts[%d]", f.Name, i)
+		key := p.ExternalID
+		if key == "" && p.ID != uuid.Nil {
+			key = p.ID.String()
+		}
+		var category []string
+		if p.CategoryID.Valid {
+			category = categories[p.CategoryID.UUID]
+		}
+
+		versions := make([]models.PromptVersion, len(p.Versions))
+		for j, v := range p.Versions {
+			versions[j] = models.PromptVersion{
+				Version:     v.Version,
+				Title:       v.Title,
+				Description: v.Description,
+				Body:        v.Body,
+				Model:       v.Model,
+				Variables:   v.Variables,
+				ChangeNote:  v.ChangeNote,
+				CreatedAt:   v.CreatedAt,
+			}
+		}
+
+		item, err := newImportItem(source, key, category, p.Favorited || favorites[p.ID], models.Prompt{
+			Title:       p.Title,
+			Description: p.Description,
+			Body:        p.Body,
+			Model:       p.Model,
+			Variables:   p.Variables,
+			Visibility:  p.Visibility,
+			CreatedAt:   p.CreatedAt,
+		}, p.Tags, versions)
+		if err != nil {
+			issues = append(issues, models.ImportIssue{Source: source, Message: err.Error()})
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, issues
+}
+
+type markdownFrontMatter struct {
+	ID          string                   `yaml:"id"`
+	ExternalID  string                   `yaml:"external_id"`
+	Title       string                   `yaml:"title"`
+	Description string                   `yaml:"description"`
+	Model       string                   `yaml:"model"`
+	Category    string                   `yaml:"category"`
+	Tags        []string                 `yaml:"tags"`
+	Visibility  string                   `yaml:"visibility"`
+	Favorite    bool                     `yaml:"favorite"`
+	Variables   models.TemplateVariables `yaml:"variables"`
+	CreatedAt   time.Time                `yaml:"created_at"`
+}
+
+// parseMarkdownPrompt reads a Markdown file with optional YAML front
+// matter. Without a title the file name is used; without an id or
+// external_id the prompt is always created.
+func parseMarkdownPrompt(f models.ImportFile) (importItem, error) {
+	text := strings.ReplaceAll(string(f.Data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var fm markdownFrontMatter
+	body := text
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		front, after, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			front, found = strings.CutSuffix(rest, "\n---")
+			after = ""
+		}
+		if !found {
+			return importItem{}, errors.New("unterminated front matter")
+		}
+		if err := yaml.Unmarshal([]byte(front), &fm); err != nil {
+			return importItem{}, fmt.Errorf("invalid front matter: %w", err)
+		}
+		body = strings.TrimLeft(after, "\n")
+	}
+
+	if fm.Title == "" {
+		fm.Title = strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+	}
+	key := fm.ExternalID
+	if key == "" {
+		key = fm.ID
+	}
+	var category []string
+	for _, name := range strings.Split(fm.Category, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			category = append(category, name)
+		}
+	}
+
+	return newImportItem(f.Name, strings.TrimSpace(key), category, fm.Favorite, models.Prompt{
+		Title:       fm.Title,
+		Description: fm.Description,
+		Body:        strings.TrimRight(body, "\n"),
+		Model:       fm.Model,
+		Variables:   fm.Variables,
+		Visibility:  fm.Visibility,
+		CreatedAt:   fm.CreatedAt,
+	}, fm.Tags, nil)
+}
+
+// newImportItem validates a prompt the same way the API would and builds
+// its revision history. Imported versions are renumbered from 1; the head is
+// appended as a final version when it differs from the last one.
+func newImportItem(source, key string, category []string, favorite bool, p models.Prompt, tags []string, versions []models.PromptVersion) (importItem, error) {
+	in := normalizePromptInput(models.PromptInput{
+		Title:       p.Title,
+		Description: p.Description,
+		Body:        p.Body,
+		Model:       p.Model,
+		Variables:   p.Variables,
+		Visibility:  p.Visibility,
+	})
+	if in.Title == "" || in.Body == "" {
+		return importItem{}, ErrInvalidPrompt
+	}
+	if in.Visibility == "" {
+		in.Visibility = models.VisibilityPrivate
+	}
+	if !validVisibility(in.Visibility) {
+		return importItem{}, ErrInvalidVisibility
+	}
+	if err := templating.Validate(in.Body, in.Variables); err != nil {
+		return importItem{}, err
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return importItem{}, err
+	}
+	for i, name := range category {
+		if category[i], err = normalizeCategoryName(name); err != nil {
+			return importItem{}, err
+		}
+	}
+
+	p.Title, p.Description, p.Body, p.Model = in.Title, in.Description, in.Body, in.Model
+	p.Variables, p.Visibility = in.Variables, in.Visibility
+
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	for i := range versions {
+		if versions[i].Variables == nil {
+			versions[i].Variables = models.TemplateVariables{}
+		}
+	}
+	head := models.PromptVersion{
+		Title:       p.Title,
+		Description: p.Description,
+		Body:        p.Body,
+		Model:       p.Model,
+		Variables:   p.Variables,
+		ChangeNote:  importChangeNote,
+		CreatedAt:   time.Now(),
+	}
+	if n := len(versions); n == 0 || !sameContent(versionPrompt(versions[n-1]), p) {
+		versions = append(versions, head)
+	}
+	for i := range versions {
+		versions[i].Version = i + 1
+		if versions[i].CreatedAt.IsZero() {
+			versions[i].CreatedAt = head.CreatedAt
+		}
+	}
+	p.CurrentVersion = len(versions)
+
+	return importItem{
+		source:   source,
+		key:      key,
+		category: category,
+		favorite: favorite,
+		prompt:   p,
+		tags:     tags,
+		versions: versions,
+	}, nil
+}
+
+func versionPrompt(v models.PromptVersion) models.Prompt {
+	return models.Prompt{
+		Title:       v.Title,
+		Description: v.Description,
+		Body:        v.Body,
+		Model:       v.Model,
+		Variables:   v.Variables,
+	}
+}
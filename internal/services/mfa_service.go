@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/congdv/go-auth/api/internal/totp"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled      = errors.New("start two-factor enrollment first")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge = errors.New("sign-in attempt is invalid or has expired; sign in again")
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts the previous and next step to absorb clock drift.
+	totpSkew = 1
+	// mfaMaxFailures caps wrong codes per user across challenges, which
+	// would otherwise only cost an attacker a fresh password login each.
+	mfaMaxFailures = 10
+	mfaLockout     = 15 * time.Minute
+)
+
+// MFARequiredError is returned by a login that passed the first factor.
+// The token identifies the pending sign-in to VerifyMFA.
+type MFARequiredError struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+type MFAService interface {
+	Status(ctx context.Context, userID uuid.UUID) (models.MFAStatus, error)
+	// Enroll creates a new secret. MFA is not enforced until Confirm.
+	Enroll(ctx context.Context, userID uuid.UUID) (models.MFAEnrollment, error)
+	// Confirm checks the first code from the app, enables MFA and returns
+	// the recovery codes. They are only shown here.
+	Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type mfaService struct {
+	cfg   *config.Config
+	users repository.UserRepo
+	mfa   repository.MFARepo
+}
+
+func NewMFAService(cfg *config.Config, users repository.UserRepo, mfa repository.MFARepo) MFAService {
+	return &mfaService{cfg: cfg, users: users, mfa: mfa}
+}
+
+func (s *mfaService) Status(ctx context.Context, userID uuid.UUID) (models.MFAStatus, error) {
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && m.ConfirmedAt == nil) {
+		return models.MFAStatus{}, nil
+	}
+	if err != nil {
+		return models.MFAStatus{}, err
+	}
+	n, err := s.mfa.RecoveryCodesRemaining(ctx, userID)
+	if err != nil {
+		return models.MFAStatus{}, err
+	}
+	return models.MFAStatus{Enabled: true, EnabledAt: m.ConfirmedAt, RecoveryCodesRemaining: n}, nil
+}
+
+func (s *mfaService) Enroll(ctx context.Context, userID uuid.UUID) (models.MFAEnrollment, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	if err := s.mfa.Enroll(ctx, userID, secret); errors.Is(err, sql.ErrNoRows) {
+		return models.MFAEnrollment{}, ErrMFAAlreadyEnabled
+	} else if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	return models.MFAEnrollment{Secret: secret, URI: totp.URI(s.cfg.MFAIssuer, u.Email, secret)}, nil
+}
+
+func (s *mfaService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := totp.Validate(m.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.Confirm(ctx, userID, step, hashes); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAAlreadyEnabled
+	} else if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.mfa.Disable(ctx, userID)
+}
+
+func (s *mfaService) verify(ctx context.Context, userID uuid.UUID, code string) error {
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && m.ConfirmedAt == nil) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	return verifyMFACode(ctx, s.mfa, m, code)
+}
+
+// verifyMFACode accepts a current TOTP code, each time step only once, or
+// an unused recovery code. Every check counts towards the user's lockout.
+func verifyMFACode(ctx context.Context, repo repository.MFARepo, m models.UserMFA, code string) error {
+	ok, until, err := repo.ClaimAttempt(ctx, m.UserID, mfaMaxFailures, mfaLockout)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ThrottledError{RetryAfter: time.Until(until)}
+	}
+	if err := checkMFACode(ctx, repo, m, code); err != nil {
+		return err
+	}
+	return repo.ResetAttempts(ctx, m.UserID)
+}
+
+func checkMFACode(ctx context.Context, repo repository.MFARepo, m models.UserMFA, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(m.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		fresh, err := repo.UseStep(ctx, m.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	ok, err := repo.UseRecoveryCode(ctx, m.UserID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted xxxx-xxxx-xxxx (60 bits each)
+// and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:12]
+		codes[i] = raw[:4] + "-" + raw[4:8] + "-" + raw[8:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
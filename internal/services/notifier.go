@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/mailer"
+)
+
+// Notifier delivers account messages to users.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, email, resetURL string, expiresAt time.Time) error
+	SendEmailVerification(ctx context.Context, email, verifyURL string, expiresAt time.Time) error
+}
+
+type mailNotifier struct {
+	mail *mailer.Mailer
+}
+
+// NewMailNotifier sends notifications as templated email. Which driver
+// delivers them, and whether delivery is queued, is up to the mailer.
+func NewMailNotifier(mail *mailer.Mailer) Notifier {
+	return &mailNotifier{mail: mail}
+}
+
+func (n *mailNotifier) SendPasswordReset(ctx context.Context, email, resetURL string, expiresAt time.Time) error {
+	return n.mail.Send(ctx, email, mailer.TemplatePasswordReset, mailer.PasswordResetData{
+		URL:       resetURL,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (n *mailNotifier) SendEmailVerification(ctx context.Context, email, verifyURL string, expiresAt time.Time) error {
+	return n.mail.Send(ctx, email, mailer.TemplateEmailVerification, mailer.EmailVerificationData{
+		URL:       verifyURL,
+		ExpiresAt: expiresAt,
+	})
+}
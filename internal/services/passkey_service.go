@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPasskeysDisabled        = errors.New("passkeys are not configured")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyInUse            = errors.New("this passkey is already registered")
+	ErrInvalidPasskeyCeremony  = errors.New("passkey request is invalid or has expired; try again")
+	ErrPasskeyVerificationFail = errors.New("passkey could not be verified")
+)
+
+const (
+	passkeyCeremonyTTL = 5 * time.Minute
+	passkeyNameMaxLen  = 64
+
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+// PasskeyLogin is a verified passkey assertion. UserVerified reports that
+// the authenticator checked a PIN or biometric, which makes the passkey a
+// second factor on its own.
+type PasskeyLogin struct {
+	UserID       uuid.UUID
+	UserVerified bool
+}
+
+type PasskeyService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.Passkey, error)
+	// BeginRegistration returns the creation options for the browser and a
+	// session token that FinishRegistration must echo back.
+	BeginRegistration(ctx context.Context, userID uuid.UUID) (string, *protocol.CredentialCreation, error)
+	FinishRegistration(ctx context.Context, userID uuid.UUID, session, name string, credential []byte) (models.Passkey, error)
+	// BeginLogin starts a usernameless login; any passkey registered here
+	// can answer it.
+	BeginLogin(ctx context.Context) (string, *protocol.CredentialAssertion, error)
+	FinishLogin(ctx context.Context, session string, credential []byte, client models.ClientInfo) (PasskeyLogin, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type passkeyService struct {
+	wa       *webauthn.WebAuthn
+	users    repository.UserRepo
+	passkeys repository.PasskeyRepo
+	events   repository.SecurityEventRepo
+}
+
+// NewPasskeyService builds the relying party from cfg. Without an RP ID
+// (no WEBAUTHN_RP_ID or FRONTEND_ORIGIN) every call is ErrPasskeysDisabled.
+func NewPasskeyService(cfg *config.Config, users repository.UserRepo, passkeys repository.PasskeyRepo, events repository.SecurityEventRepo) (PasskeyService, error) {
+	s := &passkeyService{users: users, passkeys: passkeys, events: events}
+	if cfg.WebAuthnRPID == "" {
+		return s, nil
+	}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.wa = wa
+	return s, nil
+}
+
+func (s *passkeyService) List(ctx context.Context, userID uuid.UUID) ([]models.Passkey, error) {
+	return s.passkeys.ListForUser(ctx, userID)
+}
+
+func (s *passkeyService) BeginRegistration(ctx context.Context, userID uuid.UUID) (string, *protocol.CredentialCreation, error) {
+	if s.wa == nil {
+		return "", nil, ErrPasskeysDisabled
+	}
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	creation, session, err := s.wa.BeginRegistration(user,
+		// Discoverable credentials are what make usernameless login work.
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := s.saveCeremony(ctx, &userID, ceremonyRegister, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, creation, nil
+}
+
+func (s *passkeyService) FinishRegistration(ctx context.Context, userID uuid.UUID, sessionToken, name string, credential []byte) (models.Passkey, error) {
+	if s.wa == nil {
+		return models.Passkey{}, ErrPasskeysDisabled
+	}
+	session, err := s.loadCeremony(ctx, ceremonyRegister, sessionToken)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+	if session.userID == nil || *session.userID != userID {
+		return models.Passkey{}, ErrInvalidPasskeyCeremony
+	}
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credential)
+	if err != nil {
+		return models.Passkey{}, ErrPasskeyVerificationFail
+	}
+	cred, err := s.wa.CreateCredential(user, session.data, parsed)
+	if err != nil {
+		return models.Passkey{}, ErrPasskeyVerificationFail
+	}
+
+	p := models.Passkey{
+		UserID:          userID,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       int64(cred.Authenticator.SignCount),
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+		Name:            passkeyName(name),
+	}
+	for _, t := range cred.Transport {
+		p.Transports = append(p.Transports, string(t))
+	}
+	if err := s.passkeys.Create(ctx, &p); errors.Is(err, repository.ErrPasskeyInUse) {
+		return models.Passkey{}, ErrPasskeyInUse
+	} else if err != nil {
+		return models.Passkey{}, err
+	}
+	return p, nil
+}
+
+func (s *passkeyService) BeginLogin(ctx context.Context) (string, *protocol.CredentialAssertion, error) {
+	if s.wa == nil {
+		return "", nil, ErrPasskeysDisabled
+	}
+	assertion, session, err := s.wa.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationPreferred),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := s.saveCeremony(ctx, nil, ceremonyLogin, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, assertion, nil
+}
+
+func (s *passkeyService) FinishLogin(ctx context.Context, sessionToken string, credential []byte, client models.ClientInfo) (PasskeyLogin, error) {
+	if s.wa == nil {
+		return PasskeyLogin{}, ErrPasskeysDisabled
+	}
+	session, err := s.loadCeremony(ctx, ceremonyLogin, sessionToken)
+	if err != nil {
+		return PasskeyLogin{}, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		return PasskeyLogin{}, ErrPasskeyVerificationFail
+	}
+
+	var stored models.Passkey
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		p, err := s.passkeys.FindByCredentialID(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		// The user handle is the owner's ID; a mismatch means the
+		// authenticator is answering for someone else.
+		if !bytes.Equal(userHandle, p.UserID[:]) {
+			return nil, ErrPasskeyVerificationFail
+		}
+		stored = p
+		return s.loadUser(ctx, p.UserID)
+	}
+	_, cred, err := s.wa.ValidatePasskeyLogin(findUser, session.data, parsed)
+	if err != nil {
+		return PasskeyLogin{}, ErrPasskeyVerificationFail
+	}
+
+	// A sign count that fails to move forward is accepted, since some
+	// authenticators stop counting, but the passkey is flagged for good.
+	count := int64(parsed.Response.AuthenticatorData.Counter)
+	regressed := count <= stored.SignCount && (count != 0 || stored.SignCount != 0)
+	if regressed {
+		recordSecurityEvent(ctx, s.events, stored.UserID, models.SecurityEventPasskeyCounter, client, models.EventDetails{
+			"passkey_id":      stored.ID,
+			"stored_count":    stored.SignCount,
+			"asserted_count":  count,
+			"already_flagged": stored.CloneWarning,
+		})
+	}
+	if err := s.passkeys.RecordUse(ctx, stored.ID, count, cred.Flags.BackupState, regressed); err != nil {
+		return PasskeyLogin{}, err
+	}
+	return PasskeyLogin{UserID: stored.UserID, UserVerified: cred.Flags.UserVerified}, nil
+}
+
+func (s *passkeyService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.passkeys.Delete(ctx, userID, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrPasskeyNotFound
+	case errors.Is(err, repository.ErrLastLoginMethod):
+		return ErrLastLoginMethod
+	}
+	return err
+}
+
+// ceremony is a stored webauthn.SessionData and the user it was started
+// for, if any.
+type ceremony struct {
+	userID *uuid.UUID
+	data   webauthn.SessionData
+}
+
+func (s *passkeyService) saveCeremony(ctx context.Context, userID *uuid.UUID, purpose string, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.passkeys.CreateSession(ctx, userID, purpose, hashToken(token), data, time.Now().Add(passkeyCeremonyTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *passkeyService) loadCeremony(ctx context.Context, purpose, token string) (ceremony, error) {
+	row, err := s.passkeys.ConsumeSession(ctx, purpose, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ceremony{}, ErrInvalidPasskeyCeremony
+	}
+	if err != nil {
+		return ceremony{}, err
+	}
+	c := ceremony{userID: row.UserID}
+	if err := json.Unmarshal(row.Data, &c.data); err != nil {
+		return ceremony{}, err
+	}
+	return c, nil
+}
+
+func (s *passkeyService) loadUser(ctx context.Context, userID uuid.UUID) (*webauthnUser, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.passkeys.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	wu := &webauthnUser{user: u}
+	for _, p := range passkeys {
+		wu.credentials = append(wu.credentials, passkeyCredential(p))
+	}
+	return wu, nil
+}
+
+// passkeyCredential rebuilds the library's view of a stored passkey.
+func passkeyCredential(p models.Passkey) webauthn.Credential {
+	c := webauthn.Credential{
+		ID:              p.CredentialID,
+		PublicKey:       p.PublicKey,
+		AttestationType: p.AttestationType,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: p.BackupEligible,
+			BackupState:    p.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       p.AAGUID,
+			SignCount:    uint32(p.SignCount),
+			CloneWarning: p.CloneWarning,
+		},
+	}
+	for _, t := range p.Transports {
+		c.Transport = append(c.Transport, protocol.AuthenticatorTransport(t))
+	}
+	return c
+}
+
+func passkeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Passkey"
+	}
+	if r := []rune(name); len(r) > passkeyNameMaxLen {
+		name = string(r[:passkeyNameMaxLen])
+	}
+	return name
+}
+
+// webauthnUser adapts a user and their passkeys to webauthn.User. The
+// WebAuthn user handle is the raw UUID, which never changes even if the
+// email does.
+type webauthnUser struct {
+	user        models.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *webauthnUser) WebAuthnName() string        { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Email }
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
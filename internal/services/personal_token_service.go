@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+// PersonalTokenPrefix marks personal access tokens so they can be told
+// from JWTs, and found by secret scanners.
+const PersonalTokenPrefix = "kp_"
+
+const (
+	ScopePromptsRead  = "prompts:read"
+	ScopePromptsWrite = "prompts:write"
+	ScopeRunsExecute  = "runs:execute"
+)
+
+// Scopes lists every scope a personal access token can hold.
+var Scopes = []string{ScopePromptsRead, ScopePromptsWrite, ScopeRunsExecute}
+
+const (
+	personalTokenDefaultDays = 90
+	personalTokenMaxDays     = 365
+	personalTokenNameMaxLen  = 100
+	// personalTokenPrefixLen is how much of the token is kept in clear,
+	// including PersonalTokenPrefix.
+	personalTokenPrefixLen = 11
+	// personalTokenTouchInterval limits last-used writes for busy tokens.
+	personalTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrPersonalTokenNotFound = errors.New("token not found")
+	ErrInvalidTokenName      = errors.New("token name is required")
+	ErrInvalidScope          = errors.New("unknown or missing scope; use prompts:read, prompts:write or runs:execute")
+	ErrInvalidTokenExpiry    = errors.New("expires_in_days must be between 1 and 365")
+)
+
+type PersonalTokenService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	// Create returns the token record and the token itself, which is not
+	// stored and cannot be shown again. expiresInDays of 0 means the
+	// default.
+	Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresInDays int) (models.PersonalAccessToken, string, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type personalTokenService struct {
+	tokens repository.PersonalTokenRepo
+}
+
+func NewPersonalTokenService(tokens repository.PersonalTokenRepo) PersonalTokenService {
+	return &personalTokenService{tokens: tokens}
+}
+
+func (s *personalTokenService) List(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	return s.tokens.ListForUser(ctx, userID)
+}
+
+func (s *personalTokenService) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresInDays int) (models.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > personalTokenNameMaxLen {
+		return models.PersonalAccessToken{}, "", ErrInvalidTokenName
+	}
+	if len(scopes) == 0 {
+		return models.PersonalAccessToken{}, "", ErrInvalidScope
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(Scopes, sc) {
+			return models.PersonalAccessToken{}, "", ErrInvalidScope
+		}
+	}
+	if expiresInDays == 0 {
+		expiresInDays = personalTokenDefaultDays
+	}
+	if expiresInDays < 1 || expiresInDays > personalTokenMaxDays {
+		return models.PersonalAccessToken{}, "", ErrInvalidTokenExpiry
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return models.PersonalAccessToken{}, "", err
+	}
+	raw := PersonalTokenPrefix + secret
+	slices.Sort(scopes)
+	t := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(raw),
+		Prefix:    raw[:personalTokenPrefixLen],
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: time.Now().AddDate(0, 0, expiresInDays),
+	}
+	if err := s.tokens.Create(ctx, &t); err != nil {
+		return models.PersonalAccessToken{}, "", err
+	}
+	return t, raw, nil
+}
+
+func (s *personalTokenService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.tokens.Delete(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPersonalTokenNotFound
+	}
+	return err
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/diff"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/congdv/go-auth/api/internal/templating"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPromptNotFound  = errors.New("prompt not found")
+	ErrInvalidPrompt   = errors.New("title and body are required")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrInvalidDiffMode = errors.New("diff mode must be line or word")
+
+	ErrInvalidVisibility = errors.New("visibility must be private, unlisted or public")
+)
+
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+// PromptDiff compares two versions of a prompt. Stats covers the body.
+type PromptDiff struct {
+	From        int        `json:"from"`
+	To          int        `json:"to"`
+	Mode        string     `json:"mode"`
+	Title       []diff.Op  `json:"title"`
+	Description []diff.Op  `json:"description"`
+	Body        []diff.Op  `json:"body"`
+	Model       []diff.Op  `json:"model"`
+	Stats       diff.Stats `json:"stats"`
+}
+
+const (
+	defaultPromptPageSize = 20
+	maxPromptPageSize     = 100
+)
+
+type PromptService interface {
+	Create(ctx context.Context, userID uuid.UUID, in models.PromptInput) (models.Prompt, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error)
+	List(ctx context.Context, userID uuid.UUID, params models.PromptListParams) ([]models.Prompt, int, error)
+	Update(ctx context.Context, userID, id uuid.UUID, in models.PromptInput) (models.Prompt, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+
+	ListVersions(ctx context.Context, userID, id uuid.UUID) ([]models.PromptVersion, error)
+	GetVersion(ctx context.Context, userID, id uuid.UUID, version int) (models.PromptVersion, error)
+	Diff(ctx context.Context, userID, id uuid.UUID, from, to int, mode string) (PromptDiff, error)
+	Restore(ctx context.Context, userID, id uuid.UUID, version int) (models.Prompt, error)
+
+	SetTags(ctx context.Context, userID, id uuid.UUID, tags []string) (models.Prompt, error)
+
+	Favorite(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error)
+	Unfavorite(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type promptService struct {
+	prompts       repository.PromptRepo
+	categories    repository.CategoryRepo
+	tags          repository.TagRepo
+	savedSearches repository.SavedSearchRepo
+	favorites     repository.FavoriteRepo
+	policy        EmailPolicy
+}
+
+func NewPromptService(prompts repository.PromptRepo, categories repository.CategoryRepo, tags repository.TagRepo, savedSearches repository.SavedSearchRepo, favorites repository.FavoriteRepo, policy EmailPolicy) PromptService {
+	return &promptService{
+		prompts:       prompts,
+		categories:    categories,
+		tags:          tags,
+		savedSearches: savedSearches,
+		favorites:     favorites,
+		policy:        policy,
+	}
+}
+
+func (s *promptService) Create(ctx context.Context, userID uuid.UUID, in models.PromptInput) (models.Prompt, error) {
+	in = normalizePromptInput(in)
+	if in.Title == "" || in.Body == "" {
+		return models.Prompt{}, ErrInvalidPrompt
+	}
+	if in.Visibility == "" {
+		in.Visibility = models.VisibilityPrivate
+	}
+	if !validVisibility(in.Visibility) {
+		return models.Prompt{}, ErrInvalidVisibility
+	}
+	if in.Visibility == models.VisibilityPublic {
+		if err := s.policy.RequireVerified(ctx, userID); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+
+	if err := templating.Validate(in.Body, in.Variables); err != nil {
+		return models.Prompt{}, err
+	}
+	var categoryID uuid.NullUUID
+	if in.CategoryID != nil {
+		categoryID = *in.CategoryID
+	}
+	if err := s.checkCategory(ctx, userID, categoryID); err != nil {
+		return models.Prompt{}, err
+	}
+	tags, err := normalizeTags(in.Tags)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+
+	p, err := s.prompts.Create(ctx, models.Prompt{
+		UserID:      userID,
+		Title:       in.Title,
+		Description: in.Description,
+		Body:        in.Body,
+		Model:       in.Model,
+		Variables:   in.Variables,
+		CategoryID:  categoryID,
+		Visibility:  in.Visibility,
+		Tags:        tags,
+	}, in.ChangeNote)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	return s.withTags(ctx, p)
+}
+
+// Get returns any prompt the user may read. Changes go through owned.
+func (s *promptService) Get(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	p, err := s.prompts.FindReadable(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Prompt{}, ErrPromptNotFound
+	}
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	return s.withTags(ctx, p)
+}
+
+func (s *promptService) owned(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	p, err := s.prompts.FindByID(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Prompt{}, ErrPromptNotFound
+	}
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	return s.withTags(ctx, p)
+}
+
+func (s *promptService) List(ctx context.Context, userID uuid.UUID, params models.PromptListParams) ([]models.Prompt, int, error) {
+	if params.Limit <= 0 {
+		params.Limit = defaultPromptPageSize
+	}
+	if params.Limit > maxPromptPageSize {
+		params.Limit = maxPromptPageSize
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	if err := s.checkCategory(ctx, userID, params.CategoryID); err != nil {
+		return nil, 0, err
+	}
+	if params.SavedSearchID.Valid {
+		saved, err := s.savedSearches.FindByID(ctx, userID, params.SavedSearchID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrSavedSearchNotFound
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		params.Search = &saved.Filters
+	}
+
+	prompts, total, err := s.prompts.List(ctx, userID, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachTags(ctx, prompts); err != nil {
+		return nil, 0, err
+	}
+	return prompts, total, nil
+}
+
+// Update applies every change in one transaction, so a failed update leaves
+// the prompt as it was.
+func (s *promptService) Update(ctx context.Context, userID, id uuid.UUID, in models.PromptInput) (models.Prompt, error) {
+	in = normalizePromptInput(in)
+	if in.Title == "" || in.Body == "" {
+		return models.Prompt{}, ErrInvalidPrompt
+	}
+	if in.Visibility != "" && !validVisibility(in.Visibility) {
+		return models.Prompt{}, ErrInvalidVisibility
+	}
+
+	if err := templating.Validate(in.Body, in.Variables); err != nil {
+		return models.Prompt{}, err
+	}
+
+	if in.CategoryID != nil {
+		if err := s.checkCategory(ctx, userID, *in.CategoryID); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+
+	tags, err := normalizeTags(in.Tags)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+
+	current, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	// Checked before anything is written so a refused update changes nothing.
+	if in.Visibility == models.VisibilityPublic && current.Visibility != models.VisibilityPublic {
+		if err := s.policy.RequireVerified(ctx, userID); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+
+	tx, err := s.prompts.BeginEdit(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Prompt{}, ErrPromptNotFound
+	}
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	defer tx.Rollback()
+
+	if in.Tags != nil {
+		if err := tx.SetTags(ctx, tags); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+	// Filing and visibility are not part of the revision history.
+	if in.CategoryID != nil && current.CategoryID != *in.CategoryID {
+		if err := tx.SetCategory(ctx, *in.CategoryID); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+	if in.Visibility != "" && current.Visibility != in.Visibility {
+		if err := tx.SetVisibility(ctx, in.Visibility); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+	// Saving identical content would only add a duplicate revision.
+	if current.Title != in.Title || current.Description != in.Description ||
+		current.Body != in.Body || current.Model != in.Model ||
+		!reflect.DeepEqual(current.Variables, in.Variables) {
+		if err := tx.AppendVersion(ctx, models.Prompt{
+			Title:       in.Title,
+			Description: in.Description,
+			Body:        in.Body,
+			Model:       in.Model,
+			Variables:   in.Variables,
+		}, in.ChangeNote); err != nil {
+			return models.Prompt{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Prompt{}, err
+	}
+	return s.owned(ctx, userID, id)
+}
+
+func (s *promptService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.prompts.Delete(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPromptNotFound
+	}
+	return err
+}
+
+func (s *promptService) ListVersions(ctx context.Context, userID, id uuid.UUID) ([]models.PromptVersion, error) {
+	if _, err := s.owned(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.prompts.ListVersions(ctx, userID, id)
+}
+
+func (s *promptService) GetVersion(ctx context.Context, userID, id uuid.UUID, version int) (models.PromptVersion, error) {
+	v, err := s.prompts.FindVersion(ctx, userID, id, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.owned(ctx, userID, id); err != nil {
+			return models.PromptVersion{}, err
+		}
+		return models.PromptVersion{}, ErrVersionNotFound
+	}
+	return v, err
+}
+
+func (s *promptService) Diff(ctx context.Context, userID, id uuid.UUID, from, to int, mode string) (PromptDiff, error) {
+	if mode == "" {
+		mode = DiffModeLine
+	}
+	if mode != DiffModeLine && mode != DiffModeWord {
+		return PromptDiff{}, ErrInvalidDiffMode
+	}
+
+	a, err := s.GetVersion(ctx, userID, id, from)
+	if err != nil {
+		return PromptDiff{}, err
+	}
+	b, err := s.GetVersion(ctx, userID, id, to)
+	if err != nil {
+		return PromptDiff{}, err
+	}
+
+	body := diff.Words(a.Body, b.Body)
+	if mode == DiffModeLine {
+		body = diff.Lines(a.Body, b.Body)
+	}
+
+	return PromptDiff{
+		From:        from,
+		To:          to,
+		Mode:        mode,
+		Title:       diff.Words(a.Title, b.Title),
+		Description: diff.Words(a.Description, b.Description),
+		Body:        body,
+		Model:       diff.Words(a.Model, b.Model),
+		Stats:       diff.Summarize(body),
+	}, nil
+}
+
+// Restore copies an old revision into a new head revision; history is never
+// rewritten.
+func (s *promptService) Restore(ctx context.Context, userID, id uuid.UUID, version int) (models.Prompt, error) {
+	v, err := s.GetVersion(ctx, userID, id, version)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	return s.Update(ctx, userID, id, models.PromptInput{
+		Title:       v.Title,
+		Description: v.Description,
+		Body:        v.Body,
+		Model:       v.Model,
+		Variables:   v.Variables,
+		ChangeNote:  fmt.Sprintf("Restored from version %d", v.Version),
+	})
+}
+
+func (s *promptService) SetTags(ctx context.Context, userID, id uuid.UUID, names []string) (models.Prompt, error) {
+	tags, err := normalizeTags(names)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	p, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return models.Prompt{}, err
+	}
+
+	if err := s.tags.SetPromptTags(ctx, userID, id, tags); err != nil {
+		return models.Prompt{}, err
+	}
+	return s.withTags(ctx, p)
+}
+
+// Favorite is idempotent and returns the prompt with its updated favorite
+// count.
+func (s *promptService) Favorite(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return models.Prompt{}, err
+	}
+	if err := s.favorites.Add(ctx, userID, id); err != nil {
+		return models.Prompt{}, err
+	}
+	return s.Get(ctx, userID, id)
+}
+
+// Unfavorite is idempotent and removes the favorite even when the prompt is
+// no longer readable, e.g. after its owner made it private, so it returns
+// nothing about the prompt.
+func (s *promptService) Unfavorite(ctx context.Context, userID, id uuid.UUID) error {
+	return s.favorites.Remove(ctx, userID, id)
+}
+
+func (s *promptService) withTags(ctx context.Context, p models.Prompt) (models.Prompt, error) {
+	prompts := []models.Prompt{p}
+	if err := s.attachTags(ctx, prompts); err != nil {
+		return models.Prompt{}, err
+	}
+	return prompts[0], nil
+}
+
+func (s *promptService) attachTags(ctx context.Context, prompts []models.Prompt) error {
+	ids := make([]uuid.UUID, len(prompts))
+	for i, p := range prompts {
+		ids[i] = p.ID
+	}
+	tags, err := s.tags.TagsForPrompts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range prompts {
+		prompts[i].Tags = tags[prompts[i].ID]
+		if prompts[i].Tags == nil {
+			prompts[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+func (s *promptService) checkCategory(ctx context.Context, userID uuid.UUID, categoryID uuid.NullUUID) error {
+	if !categoryID.Valid {
+		return nil
+	}
+	_, err := s.categories.FindByID(ctx, userID, categoryID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func validVisibility(v string) bool {
+	switch v {
+	case models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+func normalizePromptInput(in models.PromptInput) models.PromptInput {
+	in.Title = strings.TrimSpace(in.Title)
+	in.Visibility = strings.ToLower(strings.TrimSpace(in.Visibility))
+	in.Description = strings.TrimSpace(in.Description)
+	in.Model = strings.TrimSpace(in.Model)
+	in.ChangeNote = strings.TrimSpace(in.ChangeNote)
+	if in.Variables == nil {
+		in.Variables = models.TemplateVariables{}
+	}
+	if strings.TrimSpace(in.Body) == "" {
+		in.Body = ""
+	}
+	return in
+}
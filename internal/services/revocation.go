@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/google/uuid"
+)
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// revocationSweepInterval spaces out the memory store's eviction passes.
+const revocationSweepInterval = time.Minute
+
+// RevocationStore rejects access tokens before they expire. Entries only
+// matter for as long as a matching token could still be valid.
+type RevocationStore interface {
+	// Revoke rejects tokens carrying id, a token jti or a session ID,
+	// until the given time.
+	Revoke(ctx context.Context, id string, until time.Time) error
+	// RevokeIssuedBefore rejects every token of the user issued before t.
+	RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, t time.Time) error
+	// Revoked reports whether a token of userID issued at issuedAt and
+	// carrying any of ids has been revoked.
+	Revoked(ctx context.Context, userID uuid.UUID, issuedAt time.Time, ids ...string) (bool, error)
+}
+
+// revokeSessionAccess rejects the access tokens already handed out to a
+// session; its refresh token is revoked separately.
+func revokeSessionAccess(ctx context.Context, store RevocationStore, ttl time.Duration, sessionID uuid.UUID) error {
+	return store.Revoke(ctx, sessionID.String(), time.Now().Add(ttl))
+}
+
+// revokeUserAccess rejects every access token the user holds, including
+// one issued earlier in the same second. Tokens carry their issue time in
+// milliseconds, so one issued just after in the same millisecond is
+// rejected as well; the client gets a 401 and refreshes again.
+func revokeUserAccess(ctx context.Context, store RevocationStore, userID uuid.UUID) error {
+	return store.RevokeIssuedBefore(ctx, userID, time.Now())
+}
+
+// accessTokenTTL is how long a revocation has to be remembered.
+func accessTokenTTL(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.JWTAccessTTLMinutes) * time.Minute
+}
+
+// memoryRevocationStore keeps revocations in process. It suits a single
+// API instance; with more, use the Postgres store so all see each
+// revocation.
+type memoryRevocationStore struct {
+	// maxAge is the access token TTL, after which a watermark no longer
+	// matches any live token.
+	maxAge time.Duration
+
+	mu         sync.Mutex
+	ids        map[string]time.Time
+	watermarks map[uuid.UUID]time.Time
+	swept      time.Time
+}
+
+func NewMemoryRevocationStore(maxAge time.Duration) RevocationStore {
+	return &memoryRevocationStore{
+		maxAge:     maxAge,
+		ids:        map[string]time.Time{},
+		watermarks: map[uuid.UUID]time.Time{},
+	}
+}
+
+func (s *memoryRevocationStore) Revoke(ctx context.Context, id string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	if until.After(s.ids[id]) {
+		s.ids[id] = until
+	}
+	return nil
+}
+
+func (s *memoryRevocationStore) RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	if t.After(s.watermarks[userID]) {
+		s.watermarks[userID] = t
+	}
+	return nil
+}
+
+func (s *memoryRevocationStore) Revoked(ctx context.Context, userID uuid.UUID, issuedAt time.Time, ids ...string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		if until, ok := s.ids[id]; ok && until.After(now) {
+			return true, nil
+		}
+	}
+	wm, ok := s.watermarks[userID]
+	return ok && issuedAt.Before(wm), nil
+}
+
+// sweep drops expired entries. The caller holds mu.
+func (s *memoryRevocationStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.swept) < revocationSweepInterval {
+		return
+	}
+	s.swept = now
+	for id, until := range s.ids {
+		if !until.After(now) {
+			delete(s.ids, id)
+		}
+	}
+	for userID, wm := range s.watermarks {
+		if now.Sub(wm) > s.maxAge {
+			delete(s.watermarks, userID)
+		}
+	}
+}
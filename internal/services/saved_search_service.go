@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	ErrSavedSearchExists   = errors.New("a saved search with this name already exists")
+	ErrInvalidSavedSearch  = errors.New("saved search needs a name and at least one filter")
+)
+
+const maxSavedSearchNameLength = 100
+
+type SavedSearchService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (models.SavedSearch, error)
+	Create(ctx context.Context, userID uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error)
+	CreateFromHistory(ctx context.Context, userID uuid.UUID, name string, historyID uuid.UUID) (models.SavedSearch, error)
+	Update(ctx context.Context, userID, id uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	Run(ctx context.Context, userID, id uuid.UUID, limit int, cursor string) ([]models.SearchResult, string, error)
+}
+
+type savedSearchService struct {
+	saved   repository.SavedSearchRepo
+	history repository.SearchHistoryRepo
+	search  SearchService
+}
+
+func NewSavedSearchService(saved repository.SavedSearchRepo, history repository.SearchHistoryRepo, search SearchService) SavedSearchService {
+	return &savedSearchService{saved: saved, history: history, search: search}
+}
+
+func (s *savedSearchService) List(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error) {
+	return s.saved.List(ctx, userID)
+}
+
+func (s *savedSearchService) Get(ctx context.Context, userID, id uuid.UUID) (models.SavedSearch, error) {
+	saved, err := s.saved.FindByID(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SavedSearch{}, ErrSavedSearchNotFound
+	}
+	return saved, err
+}
+
+func (s *savedSearchService) Create(ctx context.Context, userID uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error) {
+	name, filters, err := validateSavedSearch(name, filters)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	saved, err := s.saved.Create(ctx, userID, name, filters)
+	if isUniqueViolation(err) {
+		return models.SavedSearch{}, ErrSavedSearchExists
+	}
+	return saved, err
+}
+
+// CreateFromHistory pins a previously run search under name.
+func (s *savedSearchService) CreateFromHistory(ctx context.Context, userID uuid.UUID, name string, historyID uuid.UUID) (models.SavedSearch, error) {
+	entry, err := s.history.FindByID(ctx, userID, historyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SavedSearch{}, ErrHistoryEntryNotFound
+	}
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+	return s.Create(ctx, userID, name, entry.Filters)
+}
+
+func (s *savedSearchService) Update(ctx context.Context, userID, id uuid.UUID, name string, filters models.SearchFilters) (models.SavedSearch, error) {
+	name, filters, err := validateSavedSearch(name, filters)
+	if err != nil {
+		return models.SavedSearch{}, err
+	}
+
+	saved, err := s.saved.Update(ctx, userID, id, name, filters)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return models.SavedSearch{}, ErrSavedSearchNotFound
+	case isUniqueViolation(err):
+		return models.SavedSearch{}, ErrSavedSearchExists
+	}
+	return saved, err
+}
+
+func (s *savedSearchService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.saved.Delete(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSavedSearchNotFound
+	}
+	return err
+}
+
+func (s *savedSearchService) Run(ctx context.Context, userID, id uuid.UUID, limit int, cursor string) ([]models.SearchResult, string, error) {
+	saved, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.search.Search(ctx, userID, saved.Filters, limit, cursor)
+}
+
+func validateSavedSearch(name string, filters models.SearchFilters) (string, models.SearchFilters, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxSavedSearchNameLength {
+		return "", models.SearchFilters{}, ErrInvalidSavedSearch
+	}
+
+	filters, err := normalizeSearchFilters(filters)
+	if err != nil {
+		return "", models.SearchFilters{}, err
+	}
+	if filters.IsZero() {
+		return "", models.SearchFilters{}, ErrInvalidSavedSearch
+	}
+	return name, filters, nil
+}
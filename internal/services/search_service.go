@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidDateRange     = errors.New("created_after must be before created_before")
+	ErrHistoryEntryNotFound = errors.New("search history entry not found")
+)
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 100
+)
+
+var highlightReplacer = strings.NewReplacer(repository.HighlightStart, "<mark>", repository.HighlightStop, "</mark>")
+
+type SearchService interface {
+	Search(ctx context.Context, userID uuid.UUID, filters models.SearchFilters, limit int, cursor string) ([]models.SearchResult, string, error)
+
+	History(ctx context.Context, userID uuid.UUID, limit int) ([]models.SearchHistoryEntry, error)
+	DeleteHistoryEntry(ctx context.Context, userID, id uuid.UUID) error
+	ClearHistory(ctx context.Context, userID uuid.UUID) error
+}
+
+type searchService struct {
+	cfg     *config.Config
+	search  repository.SearchRepo
+	tags    repository.TagRepo
+	history repository.SearchHistoryRepo
+}
+
+func NewSearchService(cfg *config.Config, search repository.SearchRepo, tags repository.TagRepo, history repository.SearchHistoryRepo) SearchService {
+	return &searchService{
+		cfg:     cfg,
+		search:  search,
+		tags:    tags,
+		history: history,
+	}
+}
+
+// Search runs a ranked full-text search over the user's prompts. The
+// returned cursor is empty on the last page. First pages of non-empty
+// searches are recorded in the user's history.
+func (s *searchService) Search(ctx context.Context, userID uuid.UUID, filters models.SearchFilters, limit int, cursor string) ([]models.SearchResult, string, error) {
+	filters, err := normalizeSearchFilters(filters)
+	if err != nil {
+		return nil, "", err
+	}
+	if cursor == "" && !filters.IsZero() && s.cfg.SearchHistoryLimit > 0 {
+		_ = s.history.Add(ctx, userID, filters, s.cfg.SearchHistoryLimit)
+	}
+	if limit <= 0 {
+		limit = defaultSearchResults
+	}
+	if limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	params := models.SearchParams{Filters: filters, Limit: limit + 1}
+	if cursor != "" {
+		after, err := decodeSearchCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		params.After = &after
+	}
+
+	results, err := s.search.Search(ctx, userID, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		next = encodeSearchCursor(models.SearchCursor{
+			Rank:      last.Rank,
+			UpdatedAt: last.Prompt.UpdatedAt,
+			ID:        last.Prompt.ID,
+		})
+	}
+
+	ids := make([]uuid.UUID, len(results))
+	for i, r := range results {
+		ids[i] = r.Prompt.ID
+	}
+	tags, err := s.tags.TagsForPrompts(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range results {
+		results[i].TitleHighlight = highlight(results[i].TitleHighlight)
+		results[i].Snippet = highlight(results[i].Snippet)
+		results[i].Prompt.Tags = tags[results[i].Prompt.ID]
+		if results[i].Prompt.Tags == nil {
+			results[i].Prompt.Tags = []string{}
+		}
+	}
+	return results, next, nil
+}
+
+func (s *searchService) History(ctx context.Context, userID uuid.UUID, limit int) ([]models.SearchHistoryEntry, error) {
+	if limit <= 0 || limit > s.cfg.SearchHistoryLimit {
+		limit = s.cfg.SearchHistoryLimit
+	}
+	return s.history.List(ctx, userID, limit)
+}
+
+func (s *searchService) DeleteHistoryEntry(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.history.Delete(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrHistoryEntryNotFound
+	}
+	return err
+}
+
+func (s *searchService) ClearHistory(ctx context.Context, userID uuid.UUID) error {
+	return s.history.Clear(ctx, userID)
+}
+
+func normalizeSearchFilters(f models.SearchFilters) (models.SearchFilters, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	f.Model = strings.TrimSpace(f.Model)
+	f.Visibility = strings.TrimSpace(f.Visibility)
+	if f.Visibility != "" && !validVisibility(f.Visibility) {
+		return models.SearchFilters{}, ErrInvalidVisibility
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return models.SearchFilters{}, ErrInvalidDateRange
+	}
+
+	tags, err := normalizeTags(f.Tags)
+	if err != nil {
+		return models.SearchFilters{}, err
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	f.Tags = tags
+	return f, nil
+}
+
+// highlight escapes text for HTML and turns the search markers into <mark>
+// elements.
+func highlight(s string) string {
+	return highlightReplacer.Replace(html.EscapeString(s))
+}
+
+func encodeSearchCursor(c models.SearchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSearchCursor(s string) (models.SearchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.SearchCursor{}, ErrInvalidCursor
+	}
+	var c models.SearchCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return models.SearchCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+// recordSecurityEvent stores an event for later review. The caller has
+// already acted on it, so a failed write is only logged.
+func recordSecurityEvent(ctx context.Context, events repository.SecurityEventRepo, userID uuid.UUID, typ string, client models.ClientInfo, details models.EventDetails) {
+	e := &models.SecurityEvent{
+		UserID:    &userID,
+		Type:      typ,
+		IP:        client.IP,
+		UserAgent: boundedUserAgent(client.UserAgent),
+		Details:   details,
+	}
+	if err := events.Record(ctx, e); err != nil {
+		log.Printf("security event %s for user %s: %v", typ, userID, err)
+	}
+}
+
+// maxUserAgentLen keeps a hostile User-Agent header from bloating the
+// sessions and events tables.
+const maxUserAgentLen = 512
+
+func boundedUserAgent(ua string) string {
+	if len(ua) > maxUserAgentLen {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLen], "")
+	}
+	return ua
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/congdv/go-auth/api/internal/config"
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService manages a user's signed-in devices. Revoking a session
+// kills its refresh token and the access tokens already issued to it.
+type SessionService interface {
+	// List marks the session identified by current, if any.
+	List(ctx context.Context, userID, current uuid.UUID) ([]models.Session, error)
+	Revoke(ctx context.Context, userID, sessionID uuid.UUID) error
+	// RevokeOthers signs out every session except current and reports how
+	// many were ended.
+	RevokeOthers(ctx context.Context, userID, current uuid.UUID) (int64, error)
+}
+
+type sessionService struct {
+	cfg         *config.Config
+	tokens      repository.TokenRepo
+	revocations RevocationStore
+}
+
+func NewSessionService(cfg *config.Config, tokens repository.TokenRepo, revocations RevocationStore) SessionService {
+	return &sessionService{cfg: cfg, tokens: tokens, revocations: revocations}
+}
+
+func (s *sessionService) List(ctx context.Context, userID, current uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.tokens.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	err := s.tokens.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return revokeSessionAccess(ctx, s.revocations, accessTokenTTL(s.cfg), sessionID)
+}
+
+func (s *sessionService) RevokeOthers(ctx context.Context, userID, current uuid.UUID) (int64, error) {
+	ids, err := s.tokens.RevokeOtherSessions(ctx, userID, current)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := revokeSessionAccess(ctx, s.revocations, accessTokenTTL(s.cfg), id); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(ids)), nil
+}
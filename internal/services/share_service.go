@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrShareLinkNotFound     = errors.New("share link not found")
+	ErrShareLinkExpired      = errors.New("share link has expired")
+	ErrShareLinkRevoked      = errors.New("share link has been revoked")
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	ErrSharePasswordInvalid  = errors.New("invalid share link password")
+	ErrInvalidShareExpiry    = errors.New("share link expiry must be in the future")
+)
+
+const (
+	// sharePasswordMaxAttempts guesses are allowed per link in each
+	// sharePasswordWindow.
+	sharePasswordMaxAttempts = 10
+	sharePasswordWindow      = 15 * time.Minute
+)
+
+type ShareService interface {
+	CreateLink(ctx context.Context, userID, promptID uuid.UUID, in models.ShareLinkInput) (models.ShareLink, error)
+	ListLinks(ctx context.Context, userID, promptID uuid.UUID) ([]models.ShareLink, error)
+	RevokeLink(ctx context.Context, userID, id uuid.UUID) (models.ShareLink, error)
+	Open(ctx context.Context, token, password string) (models.SharedPrompt, error)
+}
+
+type shareService struct {
+	links   repository.ShareLinkRepo
+	prompts repository.PromptRepo
+	tags    repository.TagRepo
+	policy  EmailPolicy
+}
+
+func NewShareService(links repository.ShareLinkRepo, prompts repository.PromptRepo, tags repository.TagRepo, policy EmailPolicy) ShareService {
+	return &shareService{links: links, prompts: prompts, tags: tags, policy: policy}
+}
+
+func (s *shareService) CreateLink(ctx context.Context, userID, promptID uuid.UUID, in models.ShareLinkInput) (models.ShareLink, error) {
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return models.ShareLink{}, ErrInvalidShareExpiry
+	}
+	if err := s.checkOwner(ctx, userID, promptID); err != nil {
+		return models.ShareLink{}, err
+	}
+	if err := s.policy.RequireVerified(ctx, userID); err != nil {
+		return models.ShareLink{}, err
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	l := models.ShareLink{
+		PromptID:  promptID,
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: in.ExpiresAt,
+	}
+	if in.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return models.ShareLink{}, err
+		}
+		l.PasswordHash = sql.NullString{String: string(hash), Valid: true}
+	}
+
+	l, err = s.links.Create(ctx, l)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	l.Token = token
+	return l, nil
+}
+
+func (s *shareService) ListLinks(ctx context.Context, userID, promptID uuid.UUID) ([]models.ShareLink, error) {
+	if err := s.checkOwner(ctx, userID, promptID); err != nil {
+		return nil, err
+	}
+	return s.links.ListForPrompt(ctx, userID, promptID)
+}
+
+func (s *shareService) RevokeLink(ctx context.Context, userID, id uuid.UUID) (models.ShareLink, error) {
+	l, err := s.links.Revoke(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ShareLink{}, ErrShareLinkNotFound
+	}
+	return l, err
+}
+
+// Open resolves a share token into the read-only prompt view and counts the
+// view. The password is only checked after the link itself is known to be
+// usable, and guesses are limited per link.
+func (s *shareService) Open(ctx context.Context, token, password string) (models.SharedPrompt, error) {
+	l, err := s.links.FindByTokenHash(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SharedPrompt{}, ErrShareLinkNotFound
+	}
+	if err != nil {
+		return models.SharedPrompt{}, err
+	}
+	if l.RevokedAt != nil {
+		return models.SharedPrompt{}, ErrShareLinkRevoked
+	}
+	if l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now()) {
+		return models.SharedPrompt{}, ErrShareLinkExpired
+	}
+	if l.HasPassword() {
+		if password == "" {
+			return models.SharedPrompt{}, ErrSharePasswordRequired
+		}
+		attempts, windowEnds, err := s.links.ClaimPasswordAttempt(ctx, l.ID, sharePasswordWindow)
+		if err != nil {
+			return models.SharedPrompt{}, err
+		}
+		if attempts > sharePasswordMaxAttempts {
+			return models.SharedPrompt{}, &ThrottledError{RetryAfter: time.Until(windowEnds)}
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(l.PasswordHash.String), []byte(password)); err != nil {
+			return models.SharedPrompt{}, ErrSharePasswordInvalid
+		}
+		if err := s.links.ResetPasswordAttempts(ctx, l.ID); err != nil {
+			return models.SharedPrompt{}, err
+		}
+	}
+
+	// The counter update re-checks revocation and expiry atomically.
+	if err := s.links.RecordView(ctx, l.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SharedPrompt{}, ErrShareLinkRevoked
+		}
+		return models.SharedPrompt{}, err
+	}
+
+	p, err := s.prompts.FindShared(ctx, l.PromptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SharedPrompt{}, ErrShareLinkNotFound
+	}
+	if err != nil {
+		return models.SharedPrompt{}, err
+	}
+	tags, err := s.tags.TagsForPrompts(ctx, []uuid.UUID{p.ID})
+	if err != nil {
+		return models.SharedPrompt{}, err
+	}
+
+	shared := models.SharedPrompt{
+		Title:       p.Title,
+		Description: p.Description,
+		Body:        p.Body,
+		Model:       p.Model,
+		Variables:   p.Variables,
+		Tags:        tags[p.ID],
+		UpdatedAt:   p.UpdatedAt,
+	}
+	if shared.Tags == nil {
+		shared.Tags = []string{}
+	}
+	return shared, nil
+}
+
+func (s *shareService) checkOwner(ctx context.Context, userID, promptID uuid.UUID) error {
+	_, err := s.prompts.FindByID(ctx, userID, promptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPromptNotFound
+	}
+	return err
+}
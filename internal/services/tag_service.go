@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTagNotFound  = errors.New("tag not found")
+	ErrTagExists    = errors.New("a tag with this name already exists; merge the tags instead")
+	ErrInvalidTag   = errors.New("tag names must be 1-50 characters")
+	ErrTooManyTags  = errors.New("a prompt can have at most 20 tags")
+	ErrInvalidMerge = errors.New("merge needs a target and at least one different source tag")
+)
+
+const (
+	maxTagLength       = 50
+	maxTagsPerPrompt   = 20
+	defaultTagListSize = 10
+	maxTagListSize     = 50
+)
+
+type TagService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.Tag, error)
+	Autocomplete(ctx context.Context, userID uuid.UUID, prefix string, limit int) ([]models.Tag, error)
+	Popular(ctx context.Context, userID uuid.UUID, limit int) ([]models.Tag, error)
+	Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Tag, error)
+	Merge(ctx context.Context, userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (models.Tag, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type tagService struct {
+	tags repository.TagRepo
+}
+
+func NewTagService(tags repository.TagRepo) TagService {
+	return &tagService{tags: tags}
+}
+
+func (s *tagService) List(ctx context.Context, userID uuid.UUID) ([]models.Tag, error) {
+	return s.tags.List(ctx, userID)
+}
+
+func (s *tagService) Autocomplete(ctx context.Context, userID uuid.UUID, prefix string, limit int) ([]models.Tag, error) {
+	return s.tags.Autocomplete(ctx, userID, strings.TrimSpace(prefix), clampTagLimit(limit))
+}
+
+func (s *tagService) Popular(ctx context.Context, userID uuid.UUID, limit int) ([]models.Tag, error) {
+	return s.tags.Popular(ctx, userID, clampTagLimit(limit))
+}
+
+func (s *tagService) Rename(ctx context.Context, userID, id uuid.UUID, name string) (models.Tag, error) {
+	name, ok := normalizeTagName(name)
+	if !ok {
+		return models.Tag{}, ErrInvalidTag
+	}
+
+	t, err := s.tags.Rename(ctx, userID, id, name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return models.Tag{}, ErrTagNotFound
+	case isUniqueViolation(err):
+		return models.Tag{}, ErrTagExists
+	}
+	return t, err
+}
+
+func (s *tagService) Merge(ctx context.Context, userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (models.Tag, error) {
+	seen := map[uuid.UUID]bool{targetID: true}
+	sources := make([]uuid.UUID, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		if !seen[id] {
+			seen[id] = true
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return models.Tag{}, ErrInvalidMerge
+	}
+
+	if err := s.tags.Merge(ctx, userID, targetID, sources); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Tag{}, ErrTagNotFound
+		}
+		return models.Tag{}, err
+	}
+	return s.tags.FindByID(ctx, userID, targetID)
+}
+
+func (s *tagService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	err := s.tags.Delete(ctx, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTagNotFound
+	}
+	return err
+}
+
+// normalizeTags trims and de-duplicates tag names case-insensitively,
+// keeping the first spelling seen.
+func normalizeTags(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		n, ok := normalizeTagName(n)
+		if !ok {
+			return nil, ErrInvalidTag
+		}
+		key := strings.ToLower(n)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, n)
+	}
+	if len(out) > maxTagsPerPrompt {
+		return nil, ErrTooManyTags
+	}
+	return out, nil
+}
+
+func normalizeTagName(name string) (string, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	return name, name != "" && len(name) <= maxTagLength
+}
+
+func clampTagLimit(limit int) int {
+	if limit <= 0 {
+		return defaultTagListSize
+	}
+	if limit > maxTagListSize {
+		return maxTagListSize
+	}
+	return limit
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/congdv/go-auth/api/internal/models"
+	"github.com/congdv/go-auth/api/internal/repository"
+	"github.com/congdv/go-auth/api/internal/templating"
+	"github.com/google/uuid"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+type TemplateService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.Prompt, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (models.Prompt, []templating.Placeholder, error)
+	Validate(body string, vars models.TemplateVariables) error
+	Render(ctx context.Context, userID, id uuid.UUID, values map[string]any) (string, error)
+}
+
+type templateService struct {
+	prompts PromptService
+	repo    repository.PromptRepo
+}
+
+func NewTemplateService(prompts PromptService, repo repository.PromptRepo) TemplateService {
+	return &templateService{prompts: prompts, repo: repo}
+}
+
+func (s *templateService) List(ctx context.Context, userID uuid.UUID) ([]models.Prompt, error) {
+	return s.repo.ListTemplates(ctx, userID)
+}
+
+func (s *templateService) Get(ctx context.Context, userID, id uuid.UUID) (models.Prompt, []templating.Placeholder, error) {
+	p, err := s.template(ctx, userID, id)
+	if err != nil {
+		return models.Prompt{}, nil, err
+	}
+	placeholders, _ := templating.Parse(p.Body)
+	return p, placeholders, nil
+}
+
+func (s *templateService) Validate(body string, vars models.TemplateVariables) error {
+	if len(vars) == 0 {
+		return &templating.Error{Issues: []templating.Issue{{
+			Code:    templating.CodeNoVariables,
+			Message: "a template must declare at least one variable",
+		}}}
+	}
+	return templating.Validate(body, vars)
+}
+
+func (s *templateService) Render(ctx context.Context, userID, id uuid.UUID, values map[string]any) (string, error) {
+	p, err := s.template(ctx, userID, id)
+	if err != nil {
+		return "", err
+	}
+	return templating.Render(p.Body, p.Variables, values)
+}
+
+func (s *templateService) template(ctx context.Context, userID, id uuid.UUID) (models.Prompt, error) {
+	p, err := s.prompts.Get(ctx, userID, id)
+	if errors.Is(err, ErrPromptNotFound) {
+		return models.Prompt{}, ErrTemplateNotFound
+	}
+	if err != nil {
+		return models.Prompt{}, err
+	}
+	if len(p.Variables) == 0 {
+		return models.Prompt{}, ErrTemplateNotFound
+	}
+	return p, nil
+}
@@ -0,0 +1,360 @@
+// Package templating parses and renders prompt templates that reference
+// declared variables with a {{name}} placeholder syntax.
+//
+// A backslash before {{ makes it literal, so \{{ renders as {{ and text
+// written for other template engines can be kept. Backslashes directly
+// before {{ escape each other: \\{{name}} renders a backslash followed by
+// the value.
+package templating
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/congdv/go-auth/api/internal/models"
+)
+
+const (
+	CodeMalformed      = "malformed_placeholder"
+	CodeUndeclared     = "undeclared"
+	CodeUnused         = "unused"
+	CodeDuplicate      = "duplicate"
+	CodeInvalidName    = "invalid_name"
+	CodeInvalidType    = "invalid_type"
+	CodeMissingOptions = "missing_options"
+	CodeInvalidDefault = "invalid_default"
+	CodeMissing        = "missing"
+	CodeNoVariables    = "no_variables"
+	CodeUnknown        = "unknown"
+	CodeInvalid        = "invalid"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type Issue struct {
+	Variable string `json:"variable,omitempty"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// Error collects every problem found in a template or a render request so
+// callers can report them all at once.
+type Error struct {
+	Issues []Issue `json:"issues"`
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		msgs[i] = is.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type Placeholder struct {
+	Name   string `json:"name"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	start  int
+	end    int
+}
+
+// Parse finds every {{name}} placeholder in body. Whitespace inside the
+// braces is ignored.
+func Parse(body string) ([]Placeholder, []Issue) {
+	placeholders, _, issues := parse(body)
+	return placeholders, issues
+}
+
+// escape is a run of backslashes before {{ and the text it renders as.
+type escape struct {
+	start int
+	end   int
+	text  string
+}
+
+func parse(body string) ([]Placeholder, []escape, []Issue) {
+	var (
+		placeholders []Placeholder
+		escapes      []escape
+		issues       []Issue
+	)
+	for i := 0; i < len(body); {
+		open := strings.Index(body[i:], "{{")
+		if open < 0 {
+			break
+		}
+		open += i
+
+		slashes := 0
+		for open-slashes > i && body[open-slashes-1] == '\\' {
+			slashes++
+		}
+		// An odd run leaves the braces literal.
+		literal := slashes%2 == 1
+		if slashes > 0 {
+			e := escape{start: open - slashes, end: open, text: strings.Repeat(`\`, slashes/2)}
+			if literal {
+				e.end, e.text = open+2, e.text+"{{"
+			}
+			escapes = append(escapes, e)
+		}
+		if literal {
+			i = open + 2
+			continue
+		}
+		line, col := position(body, open)
+
+		close := strings.Index(body[open+2:], "}}")
+		if close < 0 {
+			issues = append(issues, Issue{
+				Code:    CodeMalformed,
+				Message: fmt.Sprintf("unclosed placeholder at line %d, column %d", line, col),
+				Line:    line,
+				Column:  col,
+			})
+			break
+		}
+		close += open + 2
+
+		name := strings.TrimSpace(body[open+2 : close])
+		if !namePattern.MatchString(name) {
+			issues = append(issues, Issue{
+				Code:    CodeMalformed,
+				Message: fmt.Sprintf("invalid placeholder %q at line %d, column %d", body[open:close+2], line, col),
+				Line:    line,
+				Column:  col,
+			})
+		} else {
+			placeholders = append(placeholders, Placeholder{
+				Name:   name,
+				Line:   line,
+				Column: col,
+				start:  open,
+				end:    close + 2,
+			})
+		}
+		i = close + 2
+	}
+	return placeholders, escapes, issues
+}
+
+// Validate checks the variable declarations and that they match the
+// placeholders used in body. Prompts without declarations are not templates
+// and are left alone.
+func Validate(body string, vars []models.TemplateVariable) error {
+	if len(vars) == 0 {
+		return nil
+	}
+
+	placeholders, issues := Parse(body)
+	declared := map[string]bool{}
+	for _, v := range vars {
+		if !namePattern.MatchString(v.Name) {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeInvalidName, Message: fmt.Sprintf("variable name %q must start with a letter or underscore and contain only letters, digits and underscores", v.Name)})
+			continue
+		}
+		if declared[v.Name] {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeDuplicate, Message: fmt.Sprintf("variable %q is declared more than once", v.Name)})
+			continue
+		}
+		declared[v.Name] = true
+		issues = append(issues, validateDeclaration(v)...)
+	}
+
+	used := map[string]bool{}
+	for _, p := range placeholders {
+		used[p.Name] = true
+		if !declared[p.Name] {
+			issues = append(issues, Issue{
+				Variable: p.Name,
+				Code:     CodeUndeclared,
+				Message:  fmt.Sprintf("placeholder {{%s}} at line %d, column %d is not declared", p.Name, p.Line, p.Column),
+				Line:     p.Line,
+				Column:   p.Column,
+			})
+		}
+	}
+	for _, v := range vars {
+		if declared[v.Name] && !used[v.Name] {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeUnused, Message: fmt.Sprintf("variable %q is declared but never used", v.Name)})
+		}
+	}
+
+	if len(issues) > 0 {
+		return &Error{Issues: issues}
+	}
+	return nil
+}
+
+// Render substitutes values into body. Values may be JSON strings or
+// numbers; every problem with the supplied values is reported together.
+func Render(body string, vars []models.TemplateVariable, values map[string]any) (string, error) {
+	placeholders, escapes, issues := parse(body)
+
+	byName := map[string]models.TemplateVariable{}
+	for _, v := range vars {
+		byName[v.Name] = v
+	}
+
+	unknown := make([]string, 0)
+	for name := range values {
+		if _, ok := byName[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		issues = append(issues, Issue{Variable: name, Code: CodeUnknown, Message: fmt.Sprintf("variable %q is not declared by this template", name)})
+	}
+
+	resolved := map[string]string{}
+	for _, v := range vars {
+		raw, ok := values[v.Name]
+		if !ok || raw == nil {
+			switch {
+			case v.Default != nil:
+				resolved[v.Name] = *v.Default
+			case v.Optional:
+				resolved[v.Name] = ""
+			default:
+				issues = append(issues, Issue{Variable: v.Name, Code: CodeMissing, Message: fmt.Sprintf("variable %q is required", v.Name)})
+			}
+			continue
+		}
+
+		s, msg := coerce(v, raw)
+		if msg != "" {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeInvalid, Message: fmt.Sprintf("variable %q %s", v.Name, msg)})
+			continue
+		}
+		resolved[v.Name] = s
+	}
+
+	for _, p := range placeholders {
+		if _, ok := byName[p.Name]; !ok {
+			issues = append(issues, Issue{
+				Variable: p.Name,
+				Code:     CodeUndeclared,
+				Message:  fmt.Sprintf("placeholder {{%s}} at line %d, column %d is not declared", p.Name, p.Line, p.Column),
+				Line:     p.Line,
+				Column:   p.Column,
+			})
+		}
+	}
+
+	if len(issues) > 0 {
+		return "", &Error{Issues: issues}
+	}
+
+	// Placeholders and escapes are both in body order; merge them.
+	var b strings.Builder
+	last := 0
+	for len(placeholders) > 0 || len(escapes) > 0 {
+		if len(escapes) > 0 && (len(placeholders) == 0 || escapes[0].start < placeholders[0].start) {
+			e := escapes[0]
+			b.WriteString(body[last:e.start])
+			b.WriteString(e.text)
+			last, escapes = e.end, escapes[1:]
+			continue
+		}
+		p := placeholders[0]
+		b.WriteString(body[last:p.start])
+		b.WriteString(resolved[p.Name])
+		last, placeholders = p.end, placeholders[1:]
+	}
+	b.WriteString(body[last:])
+	return b.String(), nil
+}
+
+func validateDeclaration(v models.TemplateVariable) []Issue {
+	var issues []Issue
+	switch v.Type {
+	case models.VariableString, models.VariableNumber, models.VariableMultiline:
+		if len(v.Options) > 0 {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeInvalidType, Message: fmt.Sprintf("variable %q of type %s cannot declare options", v.Name, v.Type)})
+		}
+	case models.VariableEnum:
+		if len(v.Options) == 0 {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeMissingOptions, Message: fmt.Sprintf("enum variable %q must declare at least one option", v.Name)})
+		}
+		seen := map[string]bool{}
+		for _, o := range v.Options {
+			if seen[o] {
+				issues = append(issues, Issue{Variable: v.Name, Code: CodeMissingOptions, Message: fmt.Sprintf("enum variable %q repeats option %q", v.Name, o)})
+			}
+			seen[o] = true
+		}
+	default:
+		return append(issues, Issue{Variable: v.Name, Code: CodeInvalidType, Message: fmt.Sprintf("variable %q has unknown type %q; use string, number, enum or multiline", v.Name, v.Type)})
+	}
+
+	if v.Default != nil {
+		if _, msg := coerce(v, *v.Default); msg != "" {
+			issues = append(issues, Issue{Variable: v.Name, Code: CodeInvalidDefault, Message: fmt.Sprintf("default for variable %q %s", v.Name, msg)})
+		}
+	}
+	return issues
+}
+
+// coerce converts a supplied value to its rendered text, or returns a
+// message describing why it is not acceptable for v.
+func coerce(v models.TemplateVariable, raw any) (string, string) {
+	switch v.Type {
+	case models.VariableNumber:
+		switch n := raw.(type) {
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return "", "must be a finite number"
+			}
+			return strconv.FormatFloat(n, 'f', -1, 64), ""
+		case string:
+			s := strings.TrimSpace(n)
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return "", fmt.Sprintf("must be a number, got %q", n)
+			}
+			return s, ""
+		default:
+			return "", "must be a number"
+		}
+	case models.VariableEnum:
+		s, ok := raw.(string)
+		if !ok {
+			return "", "must be a string"
+		}
+		for _, o := range v.Options {
+			if s == o {
+				return s, ""
+			}
+		}
+		return "", fmt.Sprintf("must be one of %s, got %q", strings.Join(v.Options, ", "), s)
+	case models.VariableMultiline:
+		s, ok := raw.(string)
+		if !ok {
+			return "", "must be a string"
+		}
+		return s, ""
+	default:
+		s, ok := raw.(string)
+		if !ok {
+			return "", "must be a string"
+		}
+		if strings.ContainsAny(s, "\r\n") {
+			return "", "must be a single line; declare it as multiline to allow line breaks"
+		}
+		return s, ""
+	}
+}
+
+func position(body string, offset int) (int, int) {
+	line := strings.Count(body[:offset], "\n") + 1
+	col := offset - strings.LastIndex(body[:offset], "\n")
+	return line, col
+}
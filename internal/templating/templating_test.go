@@ -0,0 +1,238 @@
+package templating
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/congdv/go-auth/api/internal/models"
+)
+
+func str(s string) *string { return &s }
+
+// codes lists the issue codes of err in order, or nil when it is not an
+// *Error.
+func codes(err error) []string {
+	var terr *Error
+	if !errors.As(err, &terr) {
+		return nil
+	}
+	out := make([]string, len(terr.Issues))
+	for i, is := range terr.Issues {
+		out[i] = is.Code
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		want   []Placeholder
+		issues []Issue
+	}{
+		{
+			name: "no placeholders",
+			body: "plain { text } here",
+		},
+		{
+			name: "positions on later lines",
+			body: "Hi {{name}},\n  see {{ topic }} and {{name}}",
+			want: []Placeholder{
+				{Name: "name", Line: 1, Column: 4},
+				{Name: "topic", Line: 2, Column: 7},
+				{Name: "name", Line: 2, Column: 23},
+			},
+		},
+		{
+			name: "invalid name is reported and skipped",
+			body: "{{1st}} {{ok}}",
+			want: []Placeholder{{Name: "ok", Line: 1, Column: 9}},
+			issues: []Issue{
+				{Code: CodeMalformed, Message: `invalid placeholder "{{1st}}" at line 1, column 1`, Line: 1, Column: 1},
+			},
+		},
+		{
+			name: "unclosed placeholder stops parsing",
+			body: "{{a}}\nthen {{b",
+			want: []Placeholder{{Name: "a", Line: 1, Column: 1}},
+			issues: []Issue{
+				{Code: CodeMalformed, Message: "unclosed placeholder at line 2, column 6", Line: 2, Column: 6},
+			},
+		},
+		{
+			name: "escaped braces are not placeholders",
+			body: `\{{ user }} and \{{ unclosed`,
+		},
+		{
+			name: "escaped backslash before a placeholder",
+			body: `\\{{a}}`,
+			want: []Placeholder{{Name: "a", Line: 1, Column: 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, issues := Parse(tt.body)
+			for i := range got {
+				got[i].start, got[i].end = 0, 0
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("placeholders\ngot  %+v\nwant %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues\ngot  %+v\nwant %+v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		vars  []models.TemplateVariable
+		codes []string
+	}{
+		{
+			name: "no declarations is not a template",
+			body: "{{anything",
+		},
+		{
+			name: "valid",
+			body: "{{topic}} in {{tone}}, {{count}} points",
+			vars: []models.TemplateVariable{
+				{Name: "topic", Type: models.VariableString},
+				{Name: "tone", Type: models.VariableEnum, Options: []string{"formal", "casual"}, Default: str("casual")},
+				{Name: "count", Type: models.VariableNumber, Default: str("3")},
+			},
+		},
+		{
+			name: "escaped text needs no declaration",
+			body: `{{topic}} \{{ jinja }}`,
+			vars: []models.TemplateVariable{{Name: "topic", Type: models.VariableString}},
+		},
+		{
+			name:  "undeclared and unused",
+			body:  "{{a}}",
+			vars:  []models.TemplateVariable{{Name: "b", Type: models.VariableString}},
+			codes: []string{CodeUndeclared, CodeUnused},
+		},
+		{
+			name: "bad declarations",
+			body: "{{a}} {{b}} {{c}} {{d}}",
+			vars: []models.TemplateVariable{
+				{Name: "a", Type: models.VariableString},
+				{Name: "a", Type: models.VariableString},
+				{Name: "9x", Type: models.VariableString},
+				{Name: "b", Type: "date"},
+				{Name: "c", Type: models.VariableEnum},
+				{Name: "d", Type: models.VariableNumber, Default: str("many")},
+			},
+			codes: []string{CodeDuplicate, CodeInvalidName, CodeInvalidType, CodeMissingOptions, CodeInvalidDefault},
+		},
+		{
+			name:  "options on a string",
+			body:  "{{a}}",
+			vars:  []models.TemplateVariable{{Name: "a", Type: models.VariableString, Options: []string{"x"}}},
+			codes: []string{CodeInvalidType},
+		},
+		{
+			name:  "malformed placeholder",
+			body:  "{{a}} {{",
+			vars:  []models.TemplateVariable{{Name: "a", Type: models.VariableString}},
+			codes: []string{CodeMalformed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.body, tt.vars)
+			if got := codes(err); !reflect.DeepEqual(got, tt.codes) {
+				t.Errorf("codes = %v, want %v (err %v)", got, tt.codes, err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := []models.TemplateVariable{
+		{Name: "topic", Type: models.VariableString},
+		{Name: "tone", Type: models.VariableEnum, Options: []string{"formal", "casual"}, Default: str("casual")},
+		{Name: "count", Type: models.VariableNumber, Optional: true},
+		{Name: "notes", Type: models.VariableMultiline, Optional: true},
+	}
+	body := "{{topic}}|{{tone}}|{{ count }}|{{notes}}"
+
+	tests := []struct {
+		name   string
+		body   string
+		values map[string]any
+		want   string
+		codes  []string
+	}{
+		{
+			name:   "defaults and optional values",
+			values: map[string]any{"topic": "Go"},
+			want:   "Go|casual||",
+		},
+		{
+			name:   "supplied values",
+			values: map[string]any{"topic": "Go", "tone": "formal", "count": 2.5, "notes": "a\nb"},
+			want:   "Go|formal|2.5|a\nb",
+		},
+		{
+			name:   "number given as a string is trimmed",
+			values: map[string]any{"topic": "Go", "count": " 7 "},
+			want:   "Go|casual|7|",
+		},
+		{
+			name:   "null counts as missing",
+			values: map[string]any{"topic": nil},
+			codes:  []string{CodeMissing},
+		},
+		{
+			name: "every problem is reported",
+			values: map[string]any{
+				"topic": "two\nlines", "tone": "angry", "count": "lots", "notes": 1.0, "extra": "x",
+			},
+			codes: []string{CodeUnknown, CodeInvalid, CodeInvalid, CodeInvalid, CodeInvalid},
+		},
+		{
+			name:   "number of the wrong type",
+			values: map[string]any{"topic": "Go", "count": true},
+			codes:  []string{CodeInvalid},
+		},
+		{
+			name:   "escapes",
+			body:   `\{{ user }} {{topic}} \\{{tone}} \\\{{x}} \`,
+			values: map[string]any{"topic": "Go"},
+			want:   `{{ user }} Go \casual \{{x}} \`,
+		},
+		{
+			name:   "undeclared placeholder",
+			body:   "{{topic}} {{missing}}",
+			values: map[string]any{"topic": "Go"},
+			codes:  []string{CodeUndeclared},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.body
+			if b == "" {
+				b = body
+			}
+			got, err := Render(b, vars, tt.values)
+			if tt.codes != nil {
+				if c := codes(err); !reflect.DeepEqual(c, tt.codes) {
+					t.Errorf("codes = %v, want %v (err %v)", c, tt.codes, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
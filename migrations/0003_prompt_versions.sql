@@ -0,0 +1,34 @@
+-- Prompt versions (immutable revision history)
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS current_version INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS prompt_versions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  prompt_id UUID NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+  version INT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  model TEXT NOT NULL DEFAULT '',
+  author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  change_note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (prompt_id, version)
+);
+
+-- Revisions are never edited once written
+CREATE OR REPLACE FUNCTION prompt_versions_immutable() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'prompt_versions rows are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_prompt_versions_immutable ON prompt_versions;
+CREATE TRIGGER trg_prompt_versions_immutable
+  BEFORE UPDATE ON prompt_versions
+  FOR EACH ROW EXECUTE FUNCTION prompt_versions_immutable();
+
+-- Backfill the first revision for prompts created before versioning
+INSERT INTO prompt_versions (prompt_id, version, title, description, body, model, author_id, change_note, created_at)
+SELECT p.id, p.current_version, p.title, p.description, p.body, p.model, p.user_id, '', p.updated_at
+FROM prompts p
+ON CONFLICT (prompt_id, version) DO NOTHING;
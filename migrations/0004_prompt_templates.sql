@@ -0,0 +1,5 @@
+-- Template variable declarations on prompts and their revisions
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE prompt_versions ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_prompts_templates ON prompts(user_id) WHERE variables <> '[]'::jsonb;
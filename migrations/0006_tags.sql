@@ -0,0 +1,17 @@
+-- Tags (per user, case-insensitive names)
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name CITEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, name)
+);
+
+-- Prompt-Tags (many-to-many)
+CREATE TABLE IF NOT EXISTS prompt_tags (
+  prompt_id UUID NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (prompt_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_prompt_tags_tag_id ON prompt_tags(tag_id);
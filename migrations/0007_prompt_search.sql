@@ -0,0 +1,10 @@
+-- Full-text search over prompt title, description and body
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(body, '')), 'C')
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_prompts_search_vector ON prompts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_prompts_user_id_created_at ON prompts(user_id, created_at);
@@ -0,0 +1,33 @@
+-- Favorites (per-user bookmarks on readable prompts)
+CREATE TABLE IF NOT EXISTS favorites (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  prompt_id UUID NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, prompt_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_favorites_prompt_id ON favorites(prompt_id);
+
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS favorite_count INT NOT NULL DEFAULT 0;
+
+-- The counter is kept by a trigger so it also follows cascaded deletes. The
+-- UPDATE takes the prompt row lock, serialising concurrent toggles.
+CREATE OR REPLACE FUNCTION favorites_maintain_count() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE prompts SET favorite_count = favorite_count + 1 WHERE id = NEW.prompt_id;
+  ELSE
+    UPDATE prompts SET favorite_count = favorite_count - 1 WHERE id = OLD.prompt_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_favorites_maintain_count ON favorites;
+CREATE TRIGGER trg_favorites_maintain_count
+  AFTER INSERT OR DELETE ON favorites
+  FOR EACH ROW EXECUTE FUNCTION favorites_maintain_count();
+
+-- Resync in case favorites were written before the trigger existed
+UPDATE prompts p
+SET favorite_count = (SELECT COUNT(*) FROM favorites f WHERE f.prompt_id = p.id);
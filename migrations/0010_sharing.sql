@@ -0,0 +1,32 @@
+-- Prompt visibility
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'private';
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'prompts_visibility_check') THEN
+    ALTER TABLE prompts ADD CONSTRAINT prompts_visibility_check
+      CHECK (visibility IN ('private', 'unlisted', 'public'));
+  END IF;
+END;
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_prompts_visibility ON prompts(visibility) WHERE visibility <> 'private';
+
+-- Share links (only a hash of the token is stored)
+CREATE TABLE IF NOT EXISTS share_links (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  prompt_id UUID NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  password_hash TEXT,
+  -- Wrong password guesses, counted in a fixed window
+  password_attempts INT NOT NULL DEFAULT 0,
+  password_window_start TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ,
+  view_count INT NOT NULL DEFAULT 0,
+  last_viewed_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_prompt_id ON share_links(prompt_id);
@@ -0,0 +1,5 @@
+-- Stable external IDs let re-imports find the prompt they created before
+ALTER TABLE prompts ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompts_user_id_external_id
+  ON prompts(user_id, external_id) WHERE external_id IS NOT NULL;
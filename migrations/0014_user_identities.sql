@@ -0,0 +1,43 @@
+-- External login identities, many per user
+CREATE TABLE IF NOT EXISTS user_identities (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email CITEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_login_at TIMESTAMPTZ,
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Move the single provider stored on users into the new table
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'provider_id') THEN
+    INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+    SELECT id, provider, provider_id, email, created_at
+    FROM users
+    WHERE provider IS NOT NULL AND provider_id IS NOT NULL
+    ON CONFLICT (provider, subject) DO NOTHING;
+  END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_users_provider_id;
+ALTER TABLE users DROP COLUMN IF EXISTS provider;
+ALTER TABLE users DROP COLUMN IF EXISTS provider_id;
+
+-- Requests to link a login provider to a signed-in account (only a hash of
+-- the token is stored; single use)
+CREATE TABLE IF NOT EXISTS identity_link_intents (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_identity_link_intents_user_id ON identity_link_intents(user_id);
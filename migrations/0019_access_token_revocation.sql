@@ -0,0 +1,15 @@
+-- Access tokens and sessions revoked before their access tokens expire.
+-- id is a token jti or a session ID.
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+  id TEXT PRIMARY KEY,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+-- Access tokens issued before not_before are rejected, e.g. after a
+-- password reset or a role change.
+CREATE TABLE IF NOT EXISTS access_token_watermarks (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  not_before TIMESTAMPTZ NOT NULL
+);
@@ -1,7 +1,7 @@
 import { useState } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { Form, message } from 'antd'
-import { EyeInvisibleOutlined, EyeOutlined, GoogleOutlined } from '@ant-design/icons'
+import { EyeInvisibleOutlined, EyeOutlined, GoogleOutlined, GithubOutlined } from '@ant-design/icons'
 import { useAuth } from '../context/AuthContext'
 import { theme } from '../styles/theme'
 import {
@@ -18,6 +18,7 @@ import {
   StyledLink,
   StyledDivider,
   GoogleButton,
+  GitHubButton,
   Footer,
 } from '../styles/global'
 
@@ -41,9 +42,9 @@ export default function RegisterPage() {
     }
   }
 
-  const googleStart = () => {
+  const oauthStart = (provider: string) => {
     const base = import.meta.env.VITE_API_BASE_URL
-    window.location.href = `${base}/auth/google/start`
+    window.location.href = `${base}/auth/${provider}/start`
   }
 
   return (
@@ -154,13 +155,22 @@ export default function RegisterPage() {
 
         <GoogleButton
           icon={<GoogleOutlined />}
-          onClick={googleStart}
+          onClick={() => oauthStart('google')}
           block
           size="large"
         >
           Continue with Google
         </GoogleButton>
 
+        <GitHubButton
+          icon={<GithubOutlined />}
+          onClick={() => oauthStart('github')}
+          block
+          size="large"
+        >
+          Continue with GitHub
+        </GitHubButton>
+
         <Footer>
           © 2025 keeperprompt.com
         </Footer>
@@ -98,6 +98,12 @@ export const GoogleButton = styled(Button)`
   }
 `
 
+export const GitHubButton = styled(GoogleButton)`
+  && {
+    margin-top: ${theme.spacing.sm}px;
+  }
+`
+
 export const Footer = styled.div`
   text-align: center;
   margin-top: ${theme.spacing.xl}px;